@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-pg/pg/v10/orm"
-	"github.com/gofiber/fiber/v2/middleware/cache"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rahul2393/city-falcon-assignment/internal/alerting"
+	"github.com/rahul2393/city-falcon-assignment/internal/auth"
+	"github.com/rahul2393/city-falcon-assignment/internal/cache"
+	"github.com/rahul2393/city-falcon-assignment/internal/config"
+	"github.com/rahul2393/city-falcon-assignment/internal/fiberutil"
+	"github.com/rahul2393/city-falcon-assignment/internal/metrics"
 	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/reload"
 	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
 	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider/postgres"
+	"github.com/rahul2393/city-falcon-assignment/internal/requestlog"
+	"github.com/rahul2393/city-falcon-assignment/internal/sampler"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+	"github.com/rahul2393/city-falcon-assignment/internal/timeout"
+	"github.com/rahul2393/city-falcon-assignment/internal/tracing"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
 	"github.com/sirupsen/logrus"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -21,65 +37,206 @@ import (
 
 type Service struct {
 	provider dataprovider.Provider
-	logger   *logrus.Entry
+	// cache holds the responses to GET requests, invalidated by the writes of the entry handlers
+	cache     *cache.Cache
+	targets   *targets.Registry
+	logger    *logrus.Entry
+	redactor  *redact.Redactor
+	version   string
+	startTime time.Time
+	// adminToken allows raw query text in the X-Admin-Token header while authentication is disabled
+	adminToken string
+	// readAfterWrite is how long after a write the entry reads of the same caller go to the primary
+	readAfterWrite time.Duration
 }
 
-type Options struct {
-	DBURL             string
-	LogQuery          string
-	ListenAddressHTTP string
+// log returns the logger of the request c, which carries its request ID.
+func (s Service) log(c *fiber.Ctx) *logrus.Entry {
+	return requestlog.Logger(c.UserContext(), s.logger)
 }
 
-// MustGet retrieves the value of the environment variable named key. It panics if the variable is not present.
-func MustGet(key string) string {
-	val := os.Getenv(key)
-	if val == "" {
-		panic(fmt.Sprint("Required environment variable not set: ", key))
+// invalidateEntry removes the cached responses showing the entry id.
+func (s Service) invalidateEntry(id uuid.UUID) {
+	s.cache.Invalidate("/entries", "/entry/"+id.String())
+}
+
+// lastWriteCookie holds the time a caller last wrote an entry, in Unix milliseconds.
+const lastWriteCookie = "last_write"
+
+// noteWrite starts the read-after-write window of the caller of c by setting the lastWriteCookie of the response.
+func (s Service) noteWrite(c *fiber.Ctx) {
+	if s.readAfterWrite <= 0 {
+		return
 	}
+	c.Cookie(&fiber.Cookie{
+		Name:     lastWriteCookie,
+		Value:    strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Path:     "/",
+		MaxAge:   int((s.readAfterWrite + time.Second - 1) / time.Second),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
 
-	return val
+// wroteRecently reports whether the caller of c wrote an entry less than window ago, according to the
+// lastWriteCookie of the request.
+func wroteRecently(c *fiber.Ctx, window time.Duration) bool {
+	ms, err := strconv.ParseInt(c.Cookies(lastWriteCookie), 10, 64)
+	return err == nil && window > 0 && time.Since(time.UnixMilli(ms)) < window
+}
+
+// readContext returns the context of the entry reads of c. With consistency=strong they see every completed write,
+// bypassing the read replicas; consistency=eventual, the default, lets them lag behind except within the
+// read-after-write window of the caller.
+func (s Service) readContext(c *fiber.Ctx) (context.Context, error) {
+	switch consistency := c.Query("consistency", "eventual"); consistency {
+	case "eventual":
+		if wroteRecently(c, s.readAfterWrite) {
+			return dataprovider.WithStrongConsistency(c.UserContext()), nil
+		}
+		return c.UserContext(), nil
+	case "strong":
+		return dataprovider.WithStrongConsistency(c.UserContext()), nil
+	default:
+		return nil, fiber.NewError(http.StatusBadRequest, "unsupported consistency: "+consistency)
+	}
+}
+
+// uncachedPaths are the normalized paths, see fiberutil.NormalizePath, never served from the response cache.
+var uncachedPaths = map[string]bool{
+	"/metrics":      true,
+	"/healthz":      true,
+	"/readyz":       true,
+	"/debug/status": true,
 }
 
 func main() {
-	options := Options{
-		DBURL:             MustGet("DB_URL"),
-		LogQuery:          os.Getenv("LOG_QUERY"),
-		ListenAddressHTTP: MustGet("LISTEN_ADDRESS_HTTP"),
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
 	}
-	logger := NewLogger()
-	repo, err := postgres.NewRepository(options.DBURL, options.LogQuery != "", logger)
+}
+
+// serve runs the HTTP server until it receives SIGINT or SIGTERM. load reads the configuration again to reload it.
+func serve(cfg *config.Config, load func() (*config.Config, error)) error {
+	if err := cfg.Require("DB_URL", "LISTEN_ADDRESS_HTTP"); err != nil {
+		return err
+	}
+	logger := NewLogger(cfg)
+	redactor, err := newRedactor(cfg)
 	if err != nil {
-		logger.Fatalf("failed to connect to DB, check connection string: %v", err)
+		return err
 	}
-	svc := Service{provider: repo, logger: logger}
-	app := fiber.New()
-	app.Use(cache.New(cache.Config{
-		Next: func(c *fiber.Ctx) bool {
-			return c.Query("refresh") == "true"
-		},
-		Expiration:   30 * time.Second,
-		CacheControl: true,
-	}))
-	app.Get("/slow-queries", func(c *fiber.Ctx) error {
-		req := model.SlowQueriesRequest{
-			PageSize:   100,
-			PageOffset: 0,
-			OrderBy:    c.Query("orderBy", "pid"),
-			Filter:     c.Query("filter", ""),
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	queryLog := postgres.NewQueryLog(newQueryLogOptions(cfg))
+	repo, err := postgres.NewRepository(repositoryOptions(cfg), cfg.MigrateOnStart, queryLog, logger, redactor)
+	if err != nil {
+		return fmt.Errorf("failed to connect to DB, check connection string: %w", err)
+	}
+	registry, err := newTargetRegistry(cfg, repo, queryLog, logger, redactor)
+	if err != nil {
+		return fmt.Errorf("invalid MONITOR_TARGETS: %w", err)
+	}
+	authenticators, jwtAuth, err := newAuthenticators(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("invalid auth config: %w", err)
+	}
+	svc := Service{
+		provider:  repo,
+		targets:   registry,
+		logger:    logger,
+		redactor:  redactor,
+		version:   cfg.Version,
+		startTime: time.Now(),
+
+		adminToken:     cfg.AdminToken,
+		readAfterWrite: time.Duration(cfg.DBReadAfterWriteWindow),
+	}
+	bg := newWorkers()
+	if jwtAuth != nil {
+		bg.Go(jwtAuth.Run)
+	}
+	var slowQuerySampler *sampler.Sampler
+	if cfg.SampleInterval > 0 {
+		slowQuerySampler = sampler.New(repo, time.Duration(cfg.SampleInterval), time.Duration(cfg.SampleThreshold), time.Duration(cfg.SampleRetention), logger)
+		bg.Go(slowQuerySampler.Run)
+	}
+	// runs without rules too, so that rules can be added by reloading the configuration
+	evaluator, err := newAlertEvaluator(cfg, registry, logger, redactor)
+	if err != nil {
+		return fmt.Errorf("invalid alerting config: %w", err)
+	}
+	bg.Go(evaluator.Run)
+	reloader := reload.New(cfg, load, func(next *config.Config) error {
+		if err := evaluator.SetRules(next.AlertRules); err != nil {
+			return fmt.Errorf("ALERT_RULES: %w", err)
 		}
-		if v, err := strconv.Atoi(c.Query("pageSize", "100")); err == nil {
-			req.PageSize = v
+		level, err := logrus.ParseLevel(next.LogLevel)
+		if err != nil {
+			return fmt.Errorf("LOG_LEVEL: %w", err)
 		}
-		if v, err := strconv.Atoi(c.Query("pageOffset", "0")); err == nil {
-			req.PageOffset = v
+		logger.Logger.SetLevel(level)
+		queryLog.Set(newQueryLogOptions(next))
+		if slowQuerySampler != nil {
+			slowQuerySampler.SetThreshold(time.Duration(next.SampleThreshold))
+			slowQuerySampler.SetRetention(time.Duration(next.SampleRetention))
 		}
-		resp, err := svc.provider.SlowQuery(c.Context(), req)
-		if err != nil {
-			c.Status(http.StatusInternalServerError)
-			return nil
+		return nil
+	}, logger)
+	bg.Go(reloader.Run)
+	if svc.cache, err = newCache(cfg, reloader, logger); err != nil {
+		return fmt.Errorf("invalid cache config: %w", err)
+	}
+	defer svc.cache.Close()
+	bg.Go(svc.cache.Run)
+	prometheus.MustRegister(metrics.NewActivityCollector(registry, 5*time.Second, logger))
+	app := fiber.New()
+	app.Use(requestlog.Middleware(logger))
+	app.Use(tracing.Middleware())
+	app.Use(metrics.Middleware())
+	app.Use(timeout.Middleware(func(c *fiber.Ctx) time.Duration {
+		cfg := reloader.Config()
+		if d, ok := fiberutil.LookupRoute(cfg.RouteTimeouts, fiberutil.Path(c)); ok {
+			return time.Duration(d)
 		}
-		return c.JSON(resp)
-	})
+		return time.Duration(cfg.RequestTimeout)
+	}))
+	if len(authenticators) > 0 {
+		public := map[string]bool{}
+		for _, path := range cfg.AuthPublicPaths {
+			public[path] = true
+		}
+		app.Use(auth.Middleware(auth.Config{
+			Authenticators: authenticators,
+			Next: func(c *fiber.Ctx) bool {
+				_, ok := fiberutil.LookupRoute(public, fiberutil.Path(c))
+				return ok
+			},
+			Logger: logger,
+		}))
+	} else {
+		logger.Warn("authentication is disabled, set AUTH_API_KEYS or AUTH_JWKS to enable it")
+	}
+	app.Use(svc.cache.Middleware())
+	app.Get("/metrics", metrics.Handler())
+	app.Get("/healthz", svc.healthz)
+	app.Get("/readyz", svc.readyz)
+	app.Get("/debug/status", svc.debugStatus)
+	for _, prefix := range []string{"", "/targets/:name"} {
+		app.Get(prefix+"/slow-queries", svc.slowQueries)
+		// lists blocked backends together with the backends holding the locks they wait for
+		app.Get(prefix+"/slow-queries/blocking", svc.blockingQueries)
+		app.Get(prefix+"/replication", svc.replication)
+		app.Get(prefix+"/replication/receivers", svc.walReceivers)
+		app.Get(prefix+"/replication/slots", svc.replicationSlots)
+		app.Get(prefix+"/tables", svc.tables)
+		app.Get(prefix+"/indexes", svc.indexes)
+	}
+	// lists queries recorded by the slow query sampler
+	app.Get("/slow-queries/history", svc.slowQueryHistory)
+	app.Get("/targets", svc.listTargets)
 
 	app.Post("/entry", func(c *fiber.Ctx) error {
 		var reqBody model.Entry
@@ -87,33 +244,34 @@ func main() {
 			return err
 		}
 		reqBody.ID = uuid.New()
-		resp, err := svc.provider.Create(c.Context(), &reqBody)
+		resp, err := svc.provider.Create(c.UserContext(), &reqBody)
 		if err != nil {
+			svc.log(c).Errorf("failed to create entry: %v", err)
 			c.Status(http.StatusInternalServerError)
 			return nil
 		}
+		svc.cache.Invalidate("/entries")
+		svc.noteWrite(c)
 		return c.JSON(resp)
 	})
 
 	app.Get("/entries", func(c *fiber.Ctx) error {
-		req := model.ListEntriesRequest{
-			PageSize:   100,
-			PageOffset: 0,
-			OrderBy:    c.Query("orderBy", "create_time"),
-			Filter:     c.Query("filter", ""),
-		}
-		if v, err := strconv.Atoi(c.Query("pageSize", "100")); err == nil {
-			req.PageSize = v
-		}
-		if v, err := strconv.Atoi(c.Query("pageOffset", "0")); err == nil {
-			req.PageOffset = v
+		req := model.ListEntriesRequest{ListParams: listRequest(c, "create_time")}
+		ctx, err := svc.readContext(c)
+		if err != nil {
+			return err
 		}
-		resp, err := svc.provider.ListEntries(c.Context(), req)
+		resp, err := svc.provider.ListEntries(ctx, req)
 		if err != nil {
+			svc.log(c).Errorf("failed to list entries: %v", err)
 			c.Status(http.StatusInternalServerError)
 			return nil
 		}
-		return c.JSON(model.ListEntriesResponse{Entries: resp})
+		page := model.ListEntriesResponse{Entries: resp}
+		if fiberutil.NotModified(c, page.ETag(), time.Time{}) {
+			return c.SendStatus(http.StatusNotModified)
+		}
+		return c.JSON(page)
 	})
 
 	app.Get("/entry/:id", func(c *fiber.Ctx) error {
@@ -126,13 +284,21 @@ func main() {
 		if c.Query("showDeleted") == "true" {
 			showDeleted = true
 		}
-		resp, err := svc.provider.GetByID(c.Context(), id, showDeleted, func(query *orm.Query) {
+		ctx, err := svc.readContext(c)
+		if err != nil {
+			return err
+		}
+		resp, err := svc.provider.GetByID(ctx, id, showDeleted, func(query *orm.Query) {
 			query.WherePK()
 		})
 		if err != nil {
+			svc.log(c).Errorf("failed to get entry: %v", err)
 			c.Status(http.StatusInternalServerError)
 			return nil
 		}
+		if resp != nil && fiberutil.NotModified(c, resp.ETag(), resp.LastModified()) {
+			return c.SendStatus(http.StatusNotModified)
+		}
 		return c.JSON(resp)
 	})
 
@@ -149,14 +315,17 @@ func main() {
 			return err
 		}
 		reqBody.ID = id
-		resp, err := svc.provider.Update(c.Context(), &reqBody,
+		resp, err := svc.provider.Update(c.UserContext(), &reqBody,
 			strings.Split(c.Query("updateMask", ""), ","), func(query *orm.Query) {
 				query.WherePK()
 			})
 		if err != nil {
+			svc.log(c).Errorf("failed to update entry: %v", err)
 			c.Status(http.StatusInternalServerError)
 			return nil
 		}
+		svc.invalidateEntry(id)
+		svc.noteWrite(c)
 		return c.JSON(resp)
 	})
 
@@ -167,26 +336,180 @@ func main() {
 			c.Status(http.StatusInternalServerError)
 			return err
 		}
-		resp, err := svc.provider.Delete(c.Context(), &model.Entry{ID: id}, nil)
+		resp, err := svc.provider.Delete(c.UserContext(), &model.Entry{ID: id}, nil)
 		if err != nil {
+			svc.log(c).Errorf("failed to delete entry: %v", err)
 			c.Status(http.StatusInternalServerError)
 			return nil
 		}
+		svc.invalidateEntry(id)
+		svc.noteWrite(c)
 		return c.JSON(resp)
 	})
 
-	log.Fatal(app.Listen(":" + options.ListenAddressHTTP))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(":" + cfg.ListenAddressHTTP)
+	}()
+	select {
+	case err := <-listenErr:
+		shutdown(app, time.Duration(cfg.ShutdownTimeout), bg, registry, repo, shutdownTracing, logger)
+		return err
+	case <-ctx.Done():
+		// a second signal kills the process right away
+		stop()
+		shutdown(app, time.Duration(cfg.ShutdownTimeout), bg, registry, repo, shutdownTracing, logger)
+	}
+	return nil
+}
+
+// newRedactor builds the redactor of the query text from the REDACT_* settings.
+func newRedactor(cfg *config.Config) (*redact.Redactor, error) {
+	redactor, err := redact.New(cfg.RedactMode, cfg.RedactRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction config: %w", err)
+	}
+	return redactor, nil
+}
+
+// newQueryLogOptions selects the logged queries from the QUERY_LOG_* settings. LOG_QUERY logs every query.
+func newQueryLogOptions(cfg *config.Config) postgres.QueryLogOptions {
+	queryLog := postgres.QueryLogOptions{
+		SlowThreshold: time.Duration(cfg.QueryLogThreshold),
+		SampleRate:    cfg.QueryLogSampleRate,
+	}
+	if cfg.LogQuery {
+		queryLog.SampleRate = 1
+	}
+	return queryLog
+}
+
+// dbOptions configures the connection to the database at url.
+func dbOptions(cfg *config.Config, url string) postgres.Options {
+	return postgres.Options{
+		URL:             url,
+		PoolSize:        cfg.DBPoolSize,
+		MinIdleConns:    cfg.DBMinIdleConns,
+		DialTimeout:     time.Duration(cfg.DBDialTimeout),
+		ReadTimeout:     time.Duration(cfg.DBReadTimeout),
+		WriteTimeout:    time.Duration(cfg.DBWriteTimeout),
+		MaxRetries:      cfg.DBMaxRetries,
+		MinRetryBackoff: time.Duration(cfg.DBMinRetryBackoff),
+		MaxRetryBackoff: time.Duration(cfg.DBMaxRetryBackoff),
+		MaxPageSize:     cfg.MaxPageSize,
+	}
+}
+
+// repositoryOptions configures the connection to the service's database and its read replicas.
+func repositoryOptions(cfg *config.Config) postgres.Options {
+	opts := dbOptions(cfg, cfg.DBURL)
+	opts.ReplicaURLs = cfg.DBReplicaURLs
+	opts.ReplicaCheckInterval = time.Duration(cfg.DBReplicaCheckInterval)
+	return opts
+}
+
+// newCache builds the response cache from the CACHE_* settings, reading the lifetimes from the configuration in
+// effect.
+func newCache(cfg *config.Config, reloader *reload.Reloader, logger *logrus.Entry) (*cache.Cache, error) {
+	cacheConfig := cache.Config{
+		Next: func(c *fiber.Ctx) bool {
+			return c.Query("refresh") == "true" || c.Query("raw") == "true" || c.Query("consistency") == "strong" ||
+				wroteRecently(c, time.Duration(cfg.DBReadAfterWriteWindow)) || uncachedPaths[fiberutil.Path(c)]
+		},
+		TTL: func(c *fiber.Ctx) time.Duration {
+			cfg := reloader.Config()
+			if ttl, ok := fiberutil.LookupRoute(cfg.CacheRouteTTLs, fiberutil.Path(c)); ok {
+				return time.Duration(ttl)
+			}
+			return time.Duration(cfg.CacheTTL)
+		},
+		Logger:  logger,
+		Private: authEnabled(cfg),
+	}
+	if cfg.CacheRedisURL != "" {
+		redis, err := cache.NewRedis(cfg.CacheRedisURL)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.CacheStorage == "redis" {
+			cacheConfig.Storage = redis
+		} else {
+			cacheConfig.Broadcaster = redis
+		}
+	}
+	return cache.New(cacheConfig), nil
+}
+
+// authEnabled reports whether the requests must be authenticated, which they must once AUTH_API_KEYS or AUTH_JWKS
+// is set.
+func authEnabled(cfg *config.Config) bool {
+	return len(cfg.AuthAPIKeys) > 0 || cfg.AuthJWKS != ""
+}
+
+// newAuthenticators builds the authenticators of the AUTH_* settings, none when authentication is disabled. The JWT
+// authenticator is also returned, if any, to refresh its key set.
+func newAuthenticators(cfg *config.Config, logger *logrus.Entry) ([]auth.Authenticator, *auth.JWT, error) {
+	var authenticators []auth.Authenticator
+	if len(cfg.AuthAPIKeys) > 0 {
+		keys, err := auth.NewAPIKeys(cfg.AuthAPIKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+		authenticators = append(authenticators, keys)
+	}
+	if cfg.AuthJWKS == "" {
+		return authenticators, nil, nil
+	}
+	jwtAuth, err := auth.NewJWT(context.Background(), auth.JWTConfig{
+		JWKS:            cfg.AuthJWKS,
+		RefreshInterval: time.Duration(cfg.AuthJWKSRefreshInterval),
+		Issuer:          cfg.AuthJWTIssuer,
+		Audience:        cfg.AuthJWTAudience,
+		RolesClaim:      cfg.AuthJWTRolesClaim,
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(authenticators, jwtAuth), jwtAuth, nil
+}
+
+// newTargetRegistry builds the monitoring targets from cfg.MonitorTargets. The service's own database is
+// monitored as targets.Default unless a target of that name is configured.
+func newTargetRegistry(cfg *config.Config, repo dataprovider.Provider, queryLog *postgres.QueryLog, logger *logrus.Entry, redactor *redact.Redactor) (*targets.Registry, error) {
+	var list []*targets.Target
+	if _, ok := cfg.MonitorTargets[targets.Default]; !ok {
+		list = append(list, &targets.Target{Name: targets.Default, Monitor: repo})
+	}
+	for name, url := range cfg.MonitorTargets {
+		monitor, err := postgres.NewMonitor(dbOptions(cfg, url), queryLog, logger.WithField("target", name), redactor)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", name, err)
+		}
+		list = append(list, &targets.Target{Name: name, Monitor: monitor})
+	}
+	return targets.NewRegistry(list...)
+}
+
+// newAlertEvaluator builds the alert evaluator from the ALERT_* settings.
+func newAlertEvaluator(cfg *config.Config, registry *targets.Registry, logger *logrus.Entry, redactor *redact.Redactor) (*alerting.Evaluator, error) {
+	var notifier alerting.Notifier
+	if len(cfg.AlertWebhookURLs) > 0 {
+		notifier = alerting.NewWebhook(cfg.AlertWebhookURLs, 10*time.Second)
+	}
+	return alerting.New(cfg.AlertRules, registry, notifier, redactor, time.Duration(cfg.AlertInterval), logger)
 }
 
-func NewLogger() *logrus.Entry {
+func NewLogger(cfg *config.Config) *logrus.Entry {
 	l := logrus.New()
-	if os.Getenv("LOG_JSON") != "" {
+	if cfg.LogJSON {
 		l.Formatter = &logrus.JSONFormatter{
 			DataKey:         "data",
 			TimestampFormat: time.RFC3339Nano,
 		}
 	}
-	if lvl, err := logrus.ParseLevel(os.Getenv("LOG_LEVEL")); err == nil {
+	if lvl, err := logrus.ParseLevel(cfg.LogLevel); err == nil {
 		l.Level = lvl
 	} else {
 		l.Level = logrus.TraceLevel
@@ -194,8 +517,8 @@ func NewLogger() *logrus.Entry {
 
 	lo := l.WithFields(logrus.Fields{
 		"app": map[string]string{
-			"host":    os.Getenv("HOST"),
-			"version": os.Getenv("APP_VERSION"),
+			"host":    cfg.Host,
+			"version": cfg.Version,
 		},
 	})
 
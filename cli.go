@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/auth"
+	"github.com/rahul2393/city-falcon-assignment/internal/cli"
+	"github.com/rahul2393/city-falcon-assignment/internal/config"
+	"github.com/rahul2393/city-falcon-assignment/internal/filters"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider/postgres"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+const usage = `usage: city-falcon [config flags] [command] [arguments]
+
+commands:
+  serve                                   run the HTTP server (default)
+  migrate up | down [steps] | status      manage the schema migrations
+  entries list [flags]                    list entries
+  entries get [-show-deleted] <id>        print an entry
+  entries create [json]                   create an entry
+  entries delete <id>                     delete an entry
+  slow-queries [flags]                    print the slow queries, -watch to refresh them
+  filter validate [-resource name] <expr> check a filter expression
+  config print [-format yaml|json]        print the configuration with its secrets masked
+  api-key new | hash                      generate an API key, or hash the one read from stdin
+
+Every command is configured by the config file, environment variables and config flags of the server, run "-h"
+for the config flags and "<command> -h" for the flags of a command.`
+
+const (
+	filterUsage = "usage: filter validate [-resource name] <expr>"
+	configUsage = "usage: config print [-format yaml|json]"
+	apiKeyUsage = "usage: api-key new | hash"
+)
+
+// run loads the configuration from the config flags at the start of args and runs the command following them,
+// serve by default.
+func run(args []string) error {
+	cfg, rest, err := config.Load(args, os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Println(usage)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	configArgs := args[:len(args)-len(rest)]
+	command, args := "serve", rest
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "serve":
+		return serve(cfg, func() (*config.Config, error) {
+			cfg, _, err := config.Load(configArgs, os.Getenv)
+			return cfg, err
+		})
+	case "migrate":
+		return runMigrate(cfg, args)
+	case "entries":
+		return runEntries(cfg, args)
+	case "slow-queries":
+		return runSlowQueries(cfg, args)
+	case "filter":
+		return runFilter(args)
+	case "config":
+		return runConfig(cfg, args)
+	case "api-key":
+		return runAPIKey(args, os.Stdin, os.Stdout)
+	case "help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, usage)
+	}
+}
+
+// openRepository connects to the database of the service for the commands other than serve. Unlike serve it never
+// applies the schema migrations.
+func openRepository(cfg *config.Config, logger *logrus.Entry) (dataprovider.Provider, *redact.Redactor, error) {
+	if err := cfg.Require("DB_URL"); err != nil {
+		return nil, nil, err
+	}
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	repo, err := postgres.NewRepository(dbOptions(cfg, cfg.DBURL), false, postgres.NewQueryLog(newQueryLogOptions(cfg)), logger, redactor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to DB, check connection string: %w", err)
+	}
+	return repo, redactor, nil
+}
+
+// runEntries runs the entries command, printing the entries as JSON.
+func runEntries(cfg *config.Config, args []string) error {
+	return cli.Entries(context.Background(), args, os.Stdout, func() (dataprovider.Provider, error) {
+		repo, _, err := openRepository(cfg, NewLogger(cfg))
+		return repo, err
+	})
+}
+
+// runSlowQueries runs the slow-queries command until it is interrupted, closing the connections to the targets it
+// opened.
+func runSlowQueries(cfg *config.Config, args []string) error {
+	var repo dataprovider.Provider
+	var registry *targets.Registry
+	defer func() {
+		if registry != nil {
+			for _, t := range registry.All() {
+				if t.Monitor != repo {
+					t.Monitor.Close()
+				}
+			}
+		}
+		if repo != nil {
+			repo.Close()
+		}
+	}()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return cli.SlowQueries(ctx, args, os.Stdout, os.Stderr, func() (*targets.Registry, *redact.Redactor, error) {
+		logger := NewLogger(cfg)
+		var redactor *redact.Redactor
+		var err error
+		if repo, redactor, err = openRepository(cfg, logger); err != nil {
+			return nil, nil, err
+		}
+		if registry, err = newTargetRegistry(cfg, repo, postgres.NewQueryLog(newQueryLogOptions(cfg)), logger, redactor); err != nil {
+			return nil, nil, fmt.Errorf("invalid MONITOR_TARGETS: %w", err)
+		}
+		return registry, redactor, nil
+	})
+}
+
+// runFilter runs the filter command, checking an expression against the model of a resource without connecting to
+// the database.
+func runFilter(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return errors.New(filterUsage)
+	}
+	flags := flag.NewFlagSet("filter validate", flag.ContinueOnError)
+	resource := flags.String("resource", "entries", "resource filtered, one of "+strings.Join(filters.Resources(), ", "))
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(filterUsage)
+	}
+	query, err := filters.Validate(*resource, flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	fmt.Println(query)
+	return nil
+}
+
+// runConfig runs the config command, printing the configuration loaded from every source.
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	format := flags.String("format", "yaml", "output format, yaml or json")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	return cfg.Print(os.Stdout, *format)
+}
+
+// runAPIKey runs the api-key command, printing a new API key with the hash to set in AUTH_API_KEYS, or the hash of
+// the key read from in, so that keys never appear in the command line.
+func runAPIKey(args []string, in io.Reader, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(apiKeyUsage)
+	}
+	switch args[0] {
+	case "new":
+		key, err := auth.NewAPIKey()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "key:  %s\nhash: %s\n", key, auth.HashAPIKey(key))
+		return err
+	case "hash":
+		b, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		key := strings.TrimSpace(string(b))
+		if key == "" {
+			return errors.New("no API key on stdin")
+		}
+		_, err = fmt.Fprintln(out, auth.HashAPIKey(key))
+		return err
+	default:
+		return errors.New(apiKeyUsage)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// healthz tells that the process is alive. It never checks dependencies so that a database outage does not get
+// the service restarted.
+func (s Service) healthz(c *fiber.Ctx) error {
+	return c.SendString("ok")
+}
+
+// readyz tells whether the service can serve requests, answering 503 when its database does not answer within
+// readinessTimeout or a schema migration is not applied.
+func (s Service) readyz(c *fiber.Ctx) error {
+	resp := model.ReadinessResponse{
+		Database: targets.Check(c.UserContext(), &targets.Target{Name: "database", Monitor: s.provider}, readinessTimeout),
+	}
+	if resp.Database.Healthy {
+		if err := s.provider.CheckSchema(c.UserContext()); err != nil {
+			resp.SchemaError = err.Error()
+		} else {
+			resp.SchemaReady = true
+		}
+	}
+	resp.Ready = resp.Database.Healthy && resp.SchemaReady
+	if !resp.Ready {
+		s.log(c).Warnf("not ready: database error %q, schema error %q", resp.Database.Error, resp.SchemaError)
+		c.Status(http.StatusServiceUnavailable)
+	}
+	return c.JSON(resp)
+}
+
+// debugStatus describes the running service.
+func (s Service) debugStatus(c *fiber.Ctx) error {
+	return c.JSON(model.StatusResponse{
+		Version:       s.version,
+		GoVersion:     runtime.Version(),
+		StartTime:     s.startTime,
+		UptimeSeconds: int64(time.Since(s.startTime).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		Pool:          s.provider.PoolStats(),
+	})
+}
@@ -0,0 +1,260 @@
+// Package alerting evaluates alert rules against the activity of the monitored targets and notifies webhooks when
+// rules start and stop matching.
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/filters"
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+
+	// maxQueries bounds the number of matching queries attached to an alert.
+	maxQueries = 100
+)
+
+// Rule fires for every target where at least one backend of pg_stat_activity matches Filter, written in the
+// filter language of the slow query API.
+type Rule struct {
+	Name   string `json:"name"`
+	Filter string `json:"filter"`
+	// Target selects the targets the rule applies to like the target API parameter: empty for the default
+	// target, "*" for all of them.
+	Target string `json:"target,omitempty"`
+}
+
+// Alert is the state of a rule on a target, as sent to webhooks. An alert is sent once when it fires and once when
+// it is resolved; ID stays the same in between.
+type Alert struct {
+	ID       string                   `json:"id"`
+	Status   string                   `json:"status"`
+	Rule     string                   `json:"rule"`
+	Filter   string                   `json:"filter"`
+	Target   string                   `json:"target"`
+	StartsAt time.Time                `json:"starts_at"`
+	EndsAt   *time.Time               `json:"ends_at,omitempty"`
+	Queries  []*model.SlowQueryRecord `json:"queries,omitempty"`
+}
+
+// Notifier delivers alerts.
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+type alertKey struct {
+	rule   string
+	target string
+}
+
+type activeAlert struct {
+	Alert
+	// delivered is the last status successfully notified, empty if none was.
+	delivered string
+}
+
+// Evaluator checks its rules every interval. Alerts whose notification fails are notified again on the next
+// evaluation, and the state of a rule is kept unchanged on targets that cannot be queried.
+type Evaluator struct {
+	mu       sync.Mutex
+	rules    []Rule
+	registry *targets.Registry
+	notifier Notifier
+	redactor *redact.Redactor
+	interval time.Duration
+	logger   *logrus.Entry
+	now      func() time.Time
+
+	active map[alertKey]*activeAlert
+}
+
+// New validates rules and creates an Evaluator. notifier may be nil to only log alerts. Run must be called to
+// start evaluating.
+func New(rules []Rule, registry *targets.Registry, notifier Notifier, redactor *redact.Redactor, interval time.Duration, logger *logrus.Entry) (*Evaluator, error) {
+	if err := validate(rules, registry); err != nil {
+		return nil, err
+	}
+	return &Evaluator{
+		rules:    rules,
+		registry: registry,
+		notifier: notifier,
+		redactor: redactor,
+		interval: interval,
+		logger:   logger.WithField("component", "alerting"),
+		now:      time.Now,
+		active:   map[alertKey]*activeAlert{},
+	}, nil
+}
+
+func validate(rules []Rule, registry *targets.Registry) error {
+	var errs []error
+	names := map[string]bool{}
+	for i, r := range rules {
+		if r.Name == "" {
+			errs = append(errs, fmt.Errorf("rule #%d: missing name", i+1))
+		} else if names[r.Name] {
+			errs = append(errs, fmt.Errorf("rule %q: duplicate name", r.Name))
+		}
+		names[r.Name] = true
+		if r.Filter == "" {
+			errs = append(errs, fmt.Errorf("rule %q: missing filter", r.Name))
+		} else if _, err := filters.Validate("slow-queries", r.Filter); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: invalid filter: %v", r.Name, err))
+		}
+		if _, err := registry.Select(r.Target); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %v", r.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetRules validates rules and replaces the rules of the evaluator from the next evaluation on. The alerts of the
+// rules no longer present are resolved then.
+func (e *Evaluator) SetRules(rules []Rule) error {
+	if err := validate(rules, e.registry); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+	return nil
+}
+
+// Run evaluates the rules until ctx is cancelled.
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.evaluate(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Evaluator) evaluate(ctx context.Context) {
+	e.mu.Lock()
+	rules := e.rules
+	e.mu.Unlock()
+	names := map[string]bool{}
+	for _, rule := range rules {
+		names[rule.Name] = true
+	}
+	for key := range e.active {
+		if !names[key.rule] {
+			e.resolve(ctx, key)
+		}
+	}
+	for _, rule := range rules {
+		selected, err := e.registry.Select(rule.Target)
+		if err != nil {
+			e.logger.WithField("rule", rule.Name).Errorf("failed to select targets: %v", err)
+			continue
+		}
+		// the evaluation query is active while it runs, a rule like state = "active" would match it
+		req := model.SlowQueriesRequest{
+			ListParams:  model.ListParams{PageSize: maxQueries, OrderBy: "duration_ms DESC", Filter: rule.Filter},
+			ExcludeSelf: true,
+		}
+		records, failed := targets.FanOut(ctx, selected, func(ctx context.Context, t *targets.Target) ([]*model.SlowQueryRecord, error) {
+			records, err := t.Monitor.SlowQuery(ctx, req)
+			for _, r := range records {
+				r.Target = t.Name
+				r.Query = e.redactor.Redact(r.Query)
+			}
+			return records, err
+		})
+		matches := map[string][]*model.SlowQueryRecord{}
+		for _, r := range records {
+			matches[r.Target] = append(matches[r.Target], r)
+		}
+		for _, t := range selected {
+			if err, ok := failed[t.Name]; ok {
+				if ctx.Err() == nil {
+					e.logger.WithFields(logrus.Fields{"rule": rule.Name, "target": t.Name}).Errorf("failed to evaluate rule: %v", err)
+				}
+				continue
+			}
+			key := alertKey{rule: rule.Name, target: t.Name}
+			if len(matches[t.Name]) > 0 {
+				e.fire(ctx, key, rule, matches[t.Name])
+			} else {
+				e.resolve(ctx, key)
+			}
+		}
+	}
+}
+
+func (e *Evaluator) fire(ctx context.Context, key alertKey, rule Rule, queries []*model.SlowQueryRecord) {
+	a, ok := e.active[key]
+	if !ok {
+		startsAt := e.now()
+		a = &activeAlert{Alert: Alert{
+			ID:       fmt.Sprintf("%s/%s/%d", key.rule, key.target, startsAt.UnixNano()),
+			Rule:     rule.Name,
+			Filter:   rule.Filter,
+			Target:   key.target,
+			StartsAt: startsAt,
+		}}
+		e.active[key] = a
+	}
+	a.Status = StatusFiring
+	a.EndsAt = nil
+	a.Queries = queries
+	if a.delivered != StatusFiring {
+		e.notify(ctx, a)
+	}
+}
+
+func (e *Evaluator) resolve(ctx context.Context, key alertKey) {
+	a, ok := e.active[key]
+	if !ok {
+		return
+	}
+	if a.delivered == "" {
+		// the rule stopped matching before anyone was told it fired
+		delete(e.active, key)
+		return
+	}
+	if a.Status != StatusResolved {
+		endsAt := e.now()
+		a.Status = StatusResolved
+		a.EndsAt = &endsAt
+		a.Queries = nil
+	}
+	if e.notify(ctx, a) {
+		delete(e.active, key)
+	}
+}
+
+// notify sends a and reports whether it was delivered.
+func (e *Evaluator) notify(ctx context.Context, a *activeAlert) bool {
+	logger := e.logger.WithFields(logrus.Fields{"rule": a.Rule, "target": a.Target, "alert": a.ID})
+	if e.notifier != nil {
+		if err := e.notifier.Notify(ctx, &a.Alert); err != nil {
+			logger.Errorf("failed to notify %s alert: %v", a.Status, err)
+			return false
+		}
+	}
+	if a.Status == StatusFiring {
+		logger.Warnf("alert firing: %d matching queries", len(a.Queries))
+	} else {
+		logger.Info("alert resolved")
+	}
+	a.delivered = a.Status
+	return true
+}
@@ -0,0 +1,255 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+type fakeMonitor struct {
+	dataprovider.Monitor
+
+	records []*model.SlowQueryRecord
+	err     error
+	filters []string
+	// excludedSelf records whether the last request left out the backend of the evaluator
+	excludedSelf bool
+}
+
+func (f *fakeMonitor) SlowQuery(ctx context.Context, req model.SlowQueriesRequest) ([]*model.SlowQueryRecord, error) {
+	f.filters = append(f.filters, req.Filter)
+	f.excludedSelf = req.ExcludeSelf
+	if f.err != nil {
+		return nil, f.err
+	}
+	var records []*model.SlowQueryRecord
+	for _, r := range f.records {
+		c := *r
+		records = append(records, &c)
+	}
+	return records, nil
+}
+
+// webhookStub records the alerts it receives and answers with status.
+type webhookStub struct {
+	mu     sync.Mutex
+	alerts []*Alert
+	status int
+}
+
+func (s *webhookStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != http.StatusOK {
+		w.WriteHeader(s.status)
+		return
+	}
+	a := &Alert{}
+	if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.alerts = append(s.alerts, a)
+}
+
+func (s *webhookStub) received() []*Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Alert(nil), s.alerts...)
+}
+
+func newTestEvaluator(t *testing.T, stub *webhookStub, monitors map[string]*fakeMonitor, rules ...Rule) *Evaluator {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	var list []*targets.Target
+	for name, m := range monitors {
+		list = append(list, &targets.Target{Name: name, Monitor: m})
+	}
+	registry, err := targets.NewRegistry(list...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor, err := redact.New(redact.ModeLiterals, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(rules, registry, NewWebhook([]string{server.URL}, time.Second), redactor, time.Minute, logrus.New().WithField("test", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestNew(t *testing.T) {
+	registry, err := targets.NewRegistry(&targets.Target{Name: targets.Default})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		rules   []Rule
+		wantErr bool
+	}{
+		{"valid", []Rule{{Name: "long", Filter: `duration_ms > 60000 AND state = "active"`, Target: "*"}}, false},
+		{"missing name", []Rule{{Filter: `duration_ms > 1`}}, true},
+		{"duplicate name", []Rule{{Name: "a", Filter: `duration_ms > 1`}, {Name: "a", Filter: `duration_ms > 2`}}, true},
+		{"missing filter", []Rule{{Name: "a"}}, true},
+		{"invalid filter", []Rule{{Name: "a", Filter: `duration_ms >`}}, true},
+		{"unknown field", []Rule{{Name: "a", Filter: `runtime_ms > 1`}}, true},
+		{"unknown target", []Rule{{Name: "a", Filter: `duration_ms > 1`, Target: "nope"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rules, registry, nil, nil, time.Minute, logrus.New().WithField("test", true))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluator_FireAndResolve(t *testing.T) {
+	ctx := context.Background()
+	stub := &webhookStub{status: http.StatusOK}
+	m := &fakeMonitor{}
+	rule := Rule{Name: "long", Filter: `duration_ms > 60000 AND state = "active"`}
+	e := newTestEvaluator(t, stub, map[string]*fakeMonitor{targets.Default: m}, rule)
+
+	e.evaluate(ctx)
+	if got := stub.received(); len(got) != 0 {
+		t.Fatalf("got %d alerts without matching queries, want 0", len(got))
+	}
+	if len(m.filters) != 1 || m.filters[0] != rule.Filter {
+		t.Fatalf("filters = %q, want %q", m.filters, rule.Filter)
+	}
+	if !m.excludedSelf {
+		t.Error("the evaluation query does not exclude its own backend")
+	}
+
+	m.records = []*model.SlowQueryRecord{{PID: "42", State: "active", DurationMS: 61000, Query: "SELECT * FROM t WHERE secret = 'x'"}}
+	e.evaluate(ctx)
+	e.evaluate(ctx)
+	got := stub.received()
+	if len(got) != 1 {
+		t.Fatalf("got %d alerts while firing, want 1", len(got))
+	}
+	firing := got[0]
+	if firing.Status != StatusFiring || firing.Rule != "long" || firing.Target != targets.Default || firing.EndsAt != nil {
+		t.Errorf("firing alert = %+v", firing)
+	}
+	if len(firing.Queries) != 1 || firing.Queries[0].Query != "SELECT * FROM t WHERE secret = ?" {
+		t.Errorf("firing alert queries = %+v, want the redacted query", firing.Queries)
+	}
+
+	m.records = nil
+	e.evaluate(ctx)
+	e.evaluate(ctx)
+	got = stub.received()
+	if len(got) != 2 {
+		t.Fatalf("got %d alerts after resolving, want 2", len(got))
+	}
+	resolved := got[1]
+	if resolved.Status != StatusResolved || resolved.ID != firing.ID || resolved.EndsAt == nil {
+		t.Errorf("resolved alert = %+v, want the resolution of %s", resolved, firing.ID)
+	}
+}
+
+func TestEvaluator_RetriesFailedNotifications(t *testing.T) {
+	ctx := context.Background()
+	stub := &webhookStub{status: http.StatusServiceUnavailable}
+	m := &fakeMonitor{records: []*model.SlowQueryRecord{{PID: "1", DurationMS: 5000}}}
+	e := newTestEvaluator(t, stub, map[string]*fakeMonitor{targets.Default: m}, Rule{Name: "slow", Filter: `duration_ms > 1000`})
+
+	e.evaluate(ctx)
+	stub.mu.Lock()
+	stub.status = http.StatusOK
+	stub.mu.Unlock()
+	e.evaluate(ctx)
+	e.evaluate(ctx)
+	if got := stub.received(); len(got) != 1 || got[0].Status != StatusFiring {
+		t.Fatalf("got %+v, want a single firing alert once the webhook recovers", got)
+	}
+}
+
+func TestEvaluator_KeepsStateOfFailingTargets(t *testing.T) {
+	ctx := context.Background()
+	stub := &webhookStub{status: http.StatusOK}
+	a := &fakeMonitor{records: []*model.SlowQueryRecord{{PID: "1", DurationMS: 5000}}}
+	b := &fakeMonitor{records: []*model.SlowQueryRecord{{PID: "2", DurationMS: 5000}}}
+	e := newTestEvaluator(t, stub, map[string]*fakeMonitor{"a": a, "b": b}, Rule{Name: "slow", Filter: `duration_ms > 1000`, Target: targets.All})
+
+	e.evaluate(ctx)
+	if got := stub.received(); len(got) != 2 || got[0].Target != "a" || got[1].Target != "b" {
+		t.Fatalf("got %+v, want one alert per target", got)
+	}
+
+	b.err = errors.New("connection refused")
+	a.records = nil
+	e.evaluate(ctx)
+	got := stub.received()
+	if len(got) != 3 || got[2].Target != "a" || got[2].Status != StatusResolved {
+		t.Fatalf("got %+v, want only target a resolved", got)
+	}
+}
+
+func TestEvaluator_SetRules(t *testing.T) {
+	ctx := context.Background()
+	stub := &webhookStub{status: http.StatusOK}
+	m := &fakeMonitor{records: []*model.SlowQueryRecord{{PID: "1", DurationMS: 5000}}}
+	e := newTestEvaluator(t, stub, map[string]*fakeMonitor{targets.Default: m}, Rule{Name: "slow", Filter: `duration_ms > 1000`})
+
+	e.evaluate(ctx)
+	for _, filter := range []string{`duration_ms >`, `runtime_ms > 1000`} {
+		if err := e.SetRules([]Rule{{Name: "slow", Filter: filter}}); err == nil {
+			t.Fatalf("SetRules() accepted the invalid filter %s", filter)
+		}
+	}
+	if err := e.SetRules([]Rule{{Name: "very-slow", Filter: `duration_ms > 10000`}}); err != nil {
+		t.Fatal(err)
+	}
+	e.evaluate(ctx)
+	got := stub.received()
+	if len(got) != 3 || got[1].Rule != "slow" || got[1].Status != StatusResolved {
+		t.Fatalf("got %+v, want the alert of the removed rule resolved", got)
+	}
+	if got[2].Rule != "very-slow" || got[2].Status != StatusFiring {
+		t.Errorf("got %+v, want the new rule firing", got[2])
+	}
+}
+
+func TestWebhook_ErrorsLeaveOutTheURLToken(t *testing.T) {
+	stub := &webhookStub{status: http.StatusInternalServerError}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	closed := httptest.NewServer(stub)
+	closed.Close()
+	const token = "T000/B000/s3cr3t"
+	w := NewWebhook([]string{server.URL + "/services/" + token, closed.URL + "/hook?token=" + token, "http://[::1"}, time.Second)
+	err := w.Notify(context.Background(), &Alert{ID: "a"})
+	if err == nil {
+		t.Fatal("Notify() error = nil, want the failures of every URL")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("Notify() error = %q, leaks the token of the URLs", err)
+	}
+	for _, want := range []string{server.URL + ": unexpected status 500", closed.URL + ": Post: ", "invalid webhook URL: invalid URL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Notify() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Webhook posts alerts as JSON to a list of URLs.
+type Webhook struct {
+	urls   []string
+	client *http.Client
+}
+
+// NewWebhook returns a Webhook posting to urls, each request bounded by timeout.
+func NewWebhook(urls []string, timeout time.Duration) *Webhook {
+	return &Webhook{urls: urls, client: &http.Client{Timeout: timeout}}
+}
+
+// Notify posts alert to every URL. It fails if any URL does not answer with a 2xx status, in which case the
+// alert is retried on all of them: receivers should deduplicate on the alert ID and status.
+func (w *Webhook) Notify(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, u := range w.urls {
+		if err := w.post(ctx, u, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint(u), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post sends body to rawURL. Its errors leave out the URL, which often holds a token.
+func (w *Webhook) post(ctx context.Context, rawURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// endpoint returns the scheme and host of the webhook rawURL, leaving out its path and query, where services such as
+// Slack put the token of the hook.
+func endpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "invalid webhook URL"
+	}
+	return u.Scheme + "://" + u.Host
+}
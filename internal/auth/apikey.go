@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHeader carries the API key of a request.
+const APIKeyHeader = "X-API-Key"
+
+// hashPrefix names the hash function of the hashes of the API keys. The keys are random, so a fast hash is as
+// hard to reverse as a slow one.
+const hashPrefix = "sha256:"
+
+// APIKey is a static API key, known by its hash only.
+type APIKey struct {
+	// Name is the subject of the principal of the key, e.g. the client it was issued to.
+	Name string `json:"name"`
+	// Hash is the hash of the key returned by HashAPIKey.
+	Hash  string   `json:"hash"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// APIKeys authenticates the requests carrying a key in the APIKeyHeader header.
+type APIKeys struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	hash      []byte
+	principal *Principal
+}
+
+// NewAPIKeys fails when a key has no name, a malformed hash or the name or hash of another key.
+func NewAPIKeys(keys []APIKey) (*APIKeys, error) {
+	a := &APIKeys{}
+	names := map[string]bool{}
+	hashes := map[string]bool{}
+	for _, k := range keys {
+		if k.Name == "" {
+			return nil, fmt.Errorf("API key without a name")
+		}
+		if names[k.Name] {
+			return nil, fmt.Errorf("API key %q: duplicate name", k.Name)
+		}
+		names[k.Name] = true
+		hash, err := parseHash(k.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("API key %q: %w", k.Name, err)
+		}
+		if hashes[string(hash)] {
+			return nil, fmt.Errorf("API key %q: same key as another one", k.Name)
+		}
+		hashes[string(hash)] = true
+		a.keys = append(a.keys, apiKey{
+			hash:      hash,
+			principal: &Principal{Subject: k.Name, Method: "api_key", Roles: k.Roles},
+		})
+	}
+	return a, nil
+}
+
+func parseHash(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, hashPrefix) {
+		return nil, fmt.Errorf("want a hash of the form %s<hex>, got %q", hashPrefix, s)
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(s, hashPrefix))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("want a hash of the form %s<hex>, got %q", hashPrefix, s)
+	}
+	return hash, nil
+}
+
+// Authenticate compares the hash of the key of c with every hash in constant time.
+func (a *APIKeys) Authenticate(c *fiber.Ctx) (*Principal, error) {
+	key := c.Get(APIKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+	hash := sha256.Sum256([]byte(key))
+	var found *Principal
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash) == 1 {
+			found = k.principal
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("unknown API key: %w", ErrInvalidCredentials)
+	}
+	return found, nil
+}
+
+func (a *APIKeys) Challenge() string {
+	return `APIKey header="` + APIKeyHeader + `"`
+}
+
+// HashAPIKey returns the hash of key to configure in APIKey.Hash.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(hash[:])
+}
+
+// NewAPIKey returns a random API key of 256 bits.
+func NewAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,115 @@
+// Package auth authenticates the requests with the credentials accepted by a list of authenticators, such as API
+// keys or JWT bearer tokens, and stores the authenticated principal in the context of the request.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/requestlog"
+)
+
+// RoleAdmin is the role allowed to read the raw query text.
+const RoleAdmin = "admin"
+
+// ErrInvalidCredentials is returned, wrapped, by the authenticators for credentials they reject.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is the authenticated client of a request.
+type Principal struct {
+	// Subject names the client: the name of its API key or the subject of its token.
+	Subject string
+	// Method is the method that authenticated it, e.g. "api_key" or "jwt".
+	Method string
+	Roles  []string
+}
+
+// HasRole reports whether p was granted role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator authenticates the requests carrying the credentials of one method. Implementations must be safe
+// for concurrent use.
+type Authenticator interface {
+	// Authenticate returns the principal of the credentials of c, nil when c carries none of this method, or an
+	// error wrapping ErrInvalidCredentials when they are rejected.
+	Authenticate(c *fiber.Ctx) (*Principal, error)
+	// Challenge is the WWW-Authenticate challenge of the method, sent with 401 responses.
+	Challenge() string
+}
+
+// Config selects the authenticators of the requests.
+type Config struct {
+	// Authenticators are tried in order, the first one finding its credentials in a request authenticates it.
+	Authenticators []Authenticator
+	// Next skips the authentication of the requests it returns true for, which then have no principal.
+	Next func(c *fiber.Ctx) bool
+	// Logger logs the rejected credentials, the standard logger when nil.
+	Logger *logrus.Entry
+}
+
+type contextKey struct{}
+
+// Middleware requires every request to be authenticated by one of the authenticators of config, stores its
+// principal in the user context and adds it to the logger of the request. Requests without credentials, or whose
+// credentials are rejected, get 401 with a model.ErrorResponse body.
+func Middleware(config Config) fiber.Handler {
+	if config.Logger == nil {
+		config.Logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+	challenges := make([]string, len(config.Authenticators))
+	for i, a := range config.Authenticators {
+		challenges[i] = a.Challenge()
+	}
+	challenge := strings.Join(challenges, ", ")
+	return func(c *fiber.Ctx) error {
+		if config.Next != nil && config.Next(c) {
+			return c.Next()
+		}
+		for _, a := range config.Authenticators {
+			p, err := a.Authenticate(c)
+			if err != nil {
+				requestlog.Logger(c.UserContext(), config.Logger).WithField("component", "auth").Warnf("authentication failed: %v", err)
+				return unauthorized(c, challenge, "invalid credentials")
+			}
+			if p == nil {
+				continue
+			}
+			ctx := context.WithValue(c.UserContext(), contextKey{}, p)
+			c.SetUserContext(requestlog.WithFields(ctx, logrus.Fields{
+				"principal":   p.Subject,
+				"auth_method": p.Method,
+			}))
+			return c.Next()
+		}
+		return unauthorized(c, challenge, "authentication required")
+	}
+}
+
+func unauthorized(c *fiber.Ctx, challenge, message string) error {
+	if challenge != "" {
+		c.Set(fiber.HeaderWWWAuthenticate, challenge)
+	}
+	return c.Status(http.StatusUnauthorized).JSON(model.ErrorResponse{
+		Error:     message,
+		RequestID: requestlog.RequestID(c.UserContext()),
+	})
+}
+
+// FromContext returns the principal of the request ctx belongs to, false when it was not authenticated.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok
+}
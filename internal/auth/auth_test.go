@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/requestlog"
+)
+
+// newTestApp answers the subject and roles of the principal of each request, authenticated by authenticators.
+func newTestApp(t *testing.T, authenticators ...Authenticator) (*fiber.App, *test.Hook) {
+	t.Helper()
+	logger, hook := test.NewNullLogger()
+	app := fiber.New()
+	app.Use(requestlog.Middleware(logrus.NewEntry(logger)))
+	app.Use(Middleware(Config{
+		Authenticators: authenticators,
+		Next:           func(c *fiber.Ctx) bool { return c.Path() == "/healthz" },
+		Logger:         logrus.NewEntry(logger),
+	}))
+	handler := func(c *fiber.Ctx) error {
+		p, ok := FromContext(c.UserContext())
+		if !ok {
+			return c.SendString("anonymous")
+		}
+		return c.JSON(p)
+	}
+	app.Get("/entries", handler)
+	app.Get("/healthz", handler)
+	return app, hook
+}
+
+func do(t *testing.T, app *fiber.App, path string, header ...string) (*http.Response, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %d with an invalid body: %v", path, resp.StatusCode, err)
+	}
+	return resp, body
+}
+
+func TestMiddleware_APIKeys(t *testing.T) {
+	keys, err := NewAPIKeys([]APIKey{
+		{Name: "dashboard", Hash: HashAPIKey("dashboard-key")},
+		{Name: "ops", Hash: HashAPIKey("ops-key"), Roles: []string{RoleAdmin}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, hook := newTestApp(t, keys)
+
+	resp, body := do(t, app, "/entries", APIKeyHeader, "ops-key")
+	var p Principal
+	if err := json.Unmarshal(body, &p); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("valid key: %d %s", resp.StatusCode, body)
+	}
+	if p.Subject != "ops" || p.Method != "api_key" || !p.HasRole(RoleAdmin) {
+		t.Errorf("principal = %+v, want ops with the admin role", p)
+	}
+	access := hook.LastEntry()
+	if access.Data["principal"] != "ops" || access.Data["auth_method"] != "api_key" {
+		t.Errorf("access log fields = %v, want the principal", access.Data)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		header  []string
+		wantErr string
+	}{
+		{"missing", nil, "authentication required"},
+		{"unknown", []string{APIKeyHeader, "stolen"}, "invalid credentials"},
+	} {
+		resp, body := do(t, app, "/entries", tt.header...)
+		var e model.ErrorResponse
+		if err := json.Unmarshal(body, &e); err != nil || resp.StatusCode != http.StatusUnauthorized || e.Error != tt.wantErr {
+			t.Errorf("%s key: %d %s, want 401 %q", tt.name, resp.StatusCode, body, tt.wantErr)
+		}
+		if e.RequestID == "" || e.RequestID != resp.Header.Get(requestlog.Header) {
+			t.Errorf("%s key: request ID %q, want %q", tt.name, e.RequestID, resp.Header.Get(requestlog.Header))
+		}
+		if got := resp.Header.Get(fiber.HeaderWWWAuthenticate); got != `APIKey header="X-API-Key"` {
+			t.Errorf("%s key: WWW-Authenticate = %q", tt.name, got)
+		}
+	}
+
+	if resp, _ := do(t, app, "/healthz"); resp.StatusCode != http.StatusOK {
+		t.Errorf("skipped path: %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestNewAPIKeys(t *testing.T) {
+	hash := HashAPIKey("key")
+	for _, keys := range [][]APIKey{
+		{{Hash: hash}},
+		{{Name: "a", Hash: "key"}},
+		{{Name: "a", Hash: "sha256:abcd"}},
+		{{Name: "a", Hash: hash}, {Name: "a", Hash: HashAPIKey("other")}},
+		{{Name: "a", Hash: hash}, {Name: "b", Hash: hash}},
+	} {
+		if _, err := NewAPIKeys(keys); err == nil {
+			t.Errorf("NewAPIKeys(%+v) succeeded, want an error", keys)
+		}
+	}
+	key, err := NewAPIKey()
+	if err != nil || len(key) < 40 {
+		t.Fatalf("NewAPIKey() = %q, %v", key, err)
+	}
+	if _, err := NewAPIKeys([]APIKey{{Name: "a", Hash: HashAPIKey(key)}}); err != nil {
+		t.Errorf("NewAPIKeys() with a generated key: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// jwtLeeway tolerates the clock skew between the issuer and the service.
+	jwtLeeway = time.Minute
+	// jwksFetchTimeout bounds each fetch of a key set from a URL.
+	jwksFetchTimeout = 10 * time.Second
+	// minJWKSRefreshInterval limits the refreshes of the key set caused by tokens signed with unknown keys, so that
+	// such tokens cannot flood the issuer.
+	minJWKSRefreshInterval = 30 * time.Second
+)
+
+// jwtMethods are the accepted signature algorithms. Symmetric ones are excluded as the keys are public.
+var jwtMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// JWTConfig configures the validation of the JWT bearer tokens.
+type JWTConfig struct {
+	// JWKS is the path of a JSON Web Key Set file, or its http or https URL, holding the keys of the issuer.
+	JWKS string
+	// RefreshInterval is how often the key set is read again, 0 for never. It is also read again, at most every 30
+	// seconds, when a token is signed by a key it does not have.
+	RefreshInterval time.Duration
+	// Issuer and Audience, when set, must match the iss and aud claims of the tokens.
+	Issuer   string
+	Audience string
+	// RolesClaim names the claim holding the roles of the principal, either an array of strings or a string of
+	// space separated roles.
+	RolesClaim string
+}
+
+// JWT authenticates the requests carrying a JWT in the Authorization header with the Bearer scheme. The tokens must
+// be signed by a key of the key set and have an exp claim and a sub claim, the subject of the principal.
+type JWT struct {
+	config JWTConfig
+	parser *jwt.Parser
+	client *http.Client
+	logger *logrus.Entry
+	keys   atomic.Pointer[keySet]
+	// mu serializes the refreshes of keys, lastRefresh is the time of the last one
+	mu          sync.Mutex
+	lastRefresh time.Time
+}
+
+// NewJWT reads the key set of config, failing when it cannot.
+func NewJWT(ctx context.Context, config JWTConfig, logger *logrus.Entry) (*JWT, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtMethods),
+		jwt.WithLeeway(jwtLeeway),
+		jwt.WithExpirationRequired(),
+	}
+	if config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(config.Audience))
+	}
+	j := &JWT{
+		config: config,
+		parser: jwt.NewParser(opts...),
+		client: &http.Client{Timeout: jwksFetchTimeout},
+		logger: logger.WithField("component", "auth"),
+	}
+	if err := j.refresh(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read the JWKS %s: %w", config.JWKS, err)
+	}
+	return j, nil
+}
+
+// Run reads the key set again every RefreshInterval until ctx is cancelled, keeping the current keys when it
+// fails.
+func (j *JWT) Run(ctx context.Context) {
+	if j.config.RefreshInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(j.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.refresh(ctx); err != nil && ctx.Err() == nil {
+				j.logger.Errorf("failed to refresh the JWKS %s, keeping the current keys: %v", j.config.JWKS, err)
+			}
+		}
+	}
+}
+
+func (j *JWT) refresh(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.refreshLocked(ctx)
+}
+
+func (j *JWT) refreshLocked(ctx context.Context) error {
+	j.lastRefresh = time.Now()
+	keys, err := loadKeySet(ctx, j.client, j.config.JWKS)
+	if err != nil {
+		return err
+	}
+	j.keys.Store(&keys)
+	return nil
+}
+
+// key returns the key of ID kid, reading the key set again when it has no such key and was not read recently.
+func (j *JWT) key(ctx context.Context, kid string) (publicKey, bool) {
+	if k, ok := j.keys.Load().lookup(kid); ok {
+		return k, true
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	// another request may have refreshed the keys while this one waited
+	if k, ok := j.keys.Load().lookup(kid); ok {
+		return k, true
+	}
+	if time.Since(j.lastRefresh) < minJWKSRefreshInterval {
+		return publicKey{}, false
+	}
+	if err := j.refreshLocked(ctx); err != nil {
+		j.logger.Errorf("failed to refresh the JWKS %s for key %q: %v", j.config.JWKS, kid, err)
+		return publicKey{}, false
+	}
+	return j.keys.Load().lookup(kid)
+}
+
+func (j *JWT) Authenticate(c *fiber.Ctx) (*Principal, error) {
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+	claims := jwt.MapClaims{}
+	if _, err := j.parser.ParseWithClaims(strings.TrimSpace(token), claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		ctx, cancel := context.WithTimeout(c.UserContext(), jwksFetchTimeout)
+		defer cancel()
+		k, ok := j.key(ctx, kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if k.alg != "" && k.alg != t.Method.Alg() {
+			return nil, fmt.Errorf("key %q is restricted to %s", kid, k.alg)
+		}
+		return k.key, nil
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: token without a sub claim", ErrInvalidCredentials)
+	}
+	roles, err := j.roles(claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return &Principal{Subject: subject, Method: "jwt", Roles: roles}, nil
+}
+
+func (j *JWT) roles(claims jwt.MapClaims) ([]string, error) {
+	if j.config.RolesClaim == "" {
+		return nil, nil
+	}
+	switch v := claims[j.config.RolesClaim].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Fields(v), nil
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, r := range v {
+			s, ok := r.(string)
+			if !ok {
+				return nil, fmt.Errorf("claim %s: want strings, got %v", j.config.RolesClaim, r)
+			}
+			roles = append(roles, s)
+		}
+		return roles, nil
+	default:
+		return nil, fmt.Errorf("claim %s: want an array of strings or a string, got %v", j.config.RolesClaim, v)
+	}
+}
+
+func (j *JWT) Challenge() string {
+	return "Bearer"
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// testKeys are the private keys of a test issuer, published by their JWKS.
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ed  ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, ed: edKey}
+}
+
+func (k *testKeys) jwks(t *testing.T) []byte {
+	t.Helper()
+	b64 := base64.RawURLEncoding.EncodeToString
+	b, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "alg": "RS256", "n": b64(k.rsa.N.Bytes()), "e": b64(big.NewInt(int64(k.rsa.E)).Bytes())},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(k.ed.Public().(ed25519.PublicKey))},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWT(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, keys.jwks(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	j, err := NewJWT(context.Background(), JWTConfig{
+		JWKS:       path,
+		Issuer:     "https://issuer.example.com",
+		Audience:   "city-falcon",
+		RolesClaim: "roles",
+	}, logrus.NewEntry(logrus.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, _ := newTestApp(t, j)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   "https://issuer.example.com",
+			"aud":   "city-falcon",
+			"sub":   "alice",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": []string{RoleAdmin, "reader"},
+		}
+	}
+	with := func(k string, v interface{}) jwt.MapClaims {
+		c := valid()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	for _, token := range []string{
+		sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, valid()),
+		sign(t, jwt.SigningMethodEdDSA, "ed", keys.ed, with("roles", "admin reader")),
+	} {
+		resp, body := do(t, app, "/entries", "Authorization", "Bearer "+token)
+		var p Principal
+		if err := json.Unmarshal(body, &p); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("valid token: %d %s", resp.StatusCode, body)
+		}
+		if p.Subject != "alice" || p.Method != "jwt" || !p.HasRole(RoleAdmin) || !p.HasRole("reader") {
+			t.Errorf("principal = %+v, want alice with the admin and reader roles", p)
+		}
+	}
+
+	otherKeys := newTestKeys(t)
+	for _, tt := range []struct {
+		name  string
+		token string
+	}{
+		{"expired", sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with("exp", time.Now().Add(-time.Hour).Unix()))},
+		{"without exp", sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with("exp", nil))},
+		{"without sub", sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with("sub", nil))},
+		{"other issuer", sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with("iss", "https://evil.example.com"))},
+		{"other audience", sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with("aud", "other"))},
+		{"invalid roles", sign(t, jwt.SigningMethodRS256, "rsa", keys.rsa, with("roles", 1))},
+		{"other key", sign(t, jwt.SigningMethodRS256, "rsa", otherKeys.rsa, valid())},
+		{"unknown key", sign(t, jwt.SigningMethodRS256, "other", keys.rsa, valid())},
+		{"encryption key", sign(t, jwt.SigningMethodRS256, "enc", keys.rsa, valid())},
+		{"algorithm of another key", sign(t, jwt.SigningMethodPS256, "rsa", keys.rsa, valid())},
+		{"symmetric", sign(t, jwt.SigningMethodHS256, "rsa", keys.jwks(t), valid())},
+		{"malformed", "not-a-token"},
+	} {
+		if resp, body := do(t, app, "/entries", "Authorization", "Bearer "+tt.token); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s token: %d %s, want 401", tt.name, resp.StatusCode, body)
+		}
+	}
+	if resp, _ := do(t, app, "/entries", "Authorization", "Basic YWxpY2U6c2VjcmV0"); resp.StatusCode != http.StatusUnauthorized ||
+		resp.Header.Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("other scheme: %d %q, want 401 with a Bearer challenge", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+	}
+}
+
+func TestJWT_RefreshesURL(t *testing.T) {
+	oldKeys, newKeys := newTestKeys(t), newTestKeys(t)
+	var jwks atomic.Pointer[[]byte]
+	old := oldKeys.jwks(t)
+	jwks.Store(&old)
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(*jwks.Load())
+	}))
+	defer srv.Close()
+	j, err := NewJWT(context.Background(), JWTConfig{JWKS: srv.URL}, logrus.NewEntry(logrus.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, _ := newTestApp(t, j)
+	claims := jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+
+	// the issuer rotated its keys, keeping their IDs: the keys are only read again on unknown IDs
+	rotated := newKeys.jwks(t)
+	jwks.Store(&rotated)
+	if resp, _ := do(t, app, "/entries", "Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, "rsa", newKeys.rsa, claims)); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token of a rotated key before the refresh: %d, want 401", resp.StatusCode)
+	}
+	j.mu.Lock()
+	j.lastRefresh = time.Time{}
+	j.mu.Unlock()
+	if resp, _ := do(t, app, "/entries", "Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, "unknown", newKeys.rsa, claims)); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token of an unknown key: %d, want 401", resp.StatusCode)
+	}
+	if resp, _ := do(t, app, "/entries", "Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, "rsa", newKeys.rsa, claims)); resp.StatusCode != http.StatusOK {
+		t.Errorf("token of a rotated key after the refresh: %d, want 200", resp.StatusCode)
+	}
+	// unknown keys refresh the key set at most every minJWKSRefreshInterval
+	before := fetches.Load()
+	for i := 0; i < 3; i++ {
+		do(t, app, "/entries", "Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, "unknown", newKeys.rsa, claims))
+	}
+	if got := fetches.Load(); got != before {
+		t.Errorf("%d fetches of the key set for unknown keys right after a refresh, want 0", got-before)
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	for _, jwks := range []string{
+		`{}`,
+		`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+		`{"keys":[{"kty":"RSA","kid":"a","n":"!","e":"AQAB"}]}`,
+		`{"keys":[{"kty":"EC","kid":"a","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		`{"keys":[{"kty":"OKP","kid":"a","crv":"Ed25519","x":"AQ"}]}`,
+	} {
+		if _, err := parseKeySet([]byte(jwks)); err == nil {
+			t.Errorf("parseKeySet(%s) succeeded, want an error", jwks)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// Package cache caches the responses of GET requests, keyed by path and normalized query, and lets handlers
+// invalidate every cached response of a path after a write, on every instance of the service.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/fiberutil"
+	"github.com/rahul2393/city-falcon-assignment/internal/metrics"
+	"github.com/rahul2393/city-falcon-assignment/internal/requestlog"
+)
+
+// MaxTTL bounds the lifetime of the cached responses. The invalidation counters of the paths expire after it, so
+// that the paths written once do not use storage forever.
+const MaxTTL = 24 * time.Hour
+
+const (
+	// invalidateTimeout bounds the invalidation after a write, which must not be cancelled with its request.
+	invalidateTimeout = 5 * time.Second
+	// resubscribeDelay is the wait before subscribing again to the invalidations of the other instances.
+	resubscribeDelay = time.Second
+)
+
+// Results reported in metrics.CacheHeader.
+const (
+	resultHit    = "hit"
+	resultMiss   = "miss"
+	resultBypass = "unreachable"
+)
+
+// uncachedHeaders are the response headers specific to each response, which are not stored.
+var uncachedHeaders = map[string]bool{
+	fiber.HeaderCacheControl:  true,
+	fiber.HeaderContentLength: true,
+	fiber.HeaderDate:          true,
+	fiber.HeaderSetCookie:     true,
+	metrics.CacheHeader:       true,
+	requestlog.Header:         true,
+}
+
+// Config selects the cached responses and where they are stored.
+type Config struct {
+	// Next skips the cache for the requests it returns true for.
+	Next func(c *fiber.Ctx) bool
+	// TTL returns the lifetime of the response to c, 0 to not cache it.
+	TTL func(c *fiber.Ctx) time.Duration
+	// Storage holds the responses, a Memory when nil.
+	Storage Storage
+	// Broadcaster, when set, sends the invalidations to the other instances and applies theirs, which is only
+	// needed when Storage is not shared between them.
+	Broadcaster Broadcaster
+	// Logger logs the failures of the storage, the standard logger when nil.
+	Logger *logrus.Entry
+	// Private marks the responses as private to the client, so that shared caches, e.g. proxies, do not store them.
+	// Required when the responses are served to authenticated clients only.
+	Private bool
+}
+
+// response is a cached response.
+type response struct {
+	Status  int         `json:"status"`
+	Headers [][2]string `json:"headers"`
+	Body    []byte      `json:"body"`
+	Expires time.Time   `json:"expires"`
+}
+
+// Cache stores the successful responses to GET requests. The responses of a path are stored under the number of
+// invalidations of the path, so that invalidating it makes every response built before unreachable, even those
+// stored after the invalidation, and leaves them to expire.
+type Cache struct {
+	config Config
+	now    func() time.Time
+}
+
+func New(config Config) *Cache {
+	if config.Storage == nil {
+		config.Storage = NewMemory()
+	}
+	if config.Logger == nil {
+		config.Logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+	return &Cache{config: config, now: time.Now}
+}
+
+// Middleware serves the cached responses and caches the successful responses to GET requests, reporting the
+// result in metrics.CacheHeader. Cached responses tell clients to keep them as long as the cache does, or to
+// revalidate them every time when they carry an ETag. Responses to GET requests that are not cached tell clients
+// not to store them, or to revalidate them when they carry an ETag, unless their handler set Cache-Control.
+// Requests are served without the cache while its storage fails.
+func (s *Cache) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() != http.MethodGet {
+			return c.Next()
+		}
+		var ttl time.Duration
+		if s.config.Next == nil || !s.config.Next(c) {
+			ttl = s.config.TTL(c)
+		}
+		ctx := c.UserContext()
+		path := fiberutil.Path(c)
+		var generation uint64
+		if ttl > 0 {
+			var err error
+			if generation, err = s.generation(ctx, path); err != nil {
+				s.log(ctx).Warnf("cache storage failed, serving without the cache: %v", err)
+				ttl = 0
+			}
+		}
+		if ttl <= 0 {
+			c.Set(metrics.CacheHeader, resultBypass)
+			err := c.Next()
+			if err == nil && len(c.Response().Header.Peek(fiber.HeaderCacheControl)) == 0 {
+				if c.GetRespHeader(fiber.HeaderETag) != "" {
+					s.setCacheControl(c, "no-cache")
+				} else {
+					c.Set(fiber.HeaderCacheControl, "no-store")
+				}
+			}
+			return err
+		}
+
+		key := responseKey(generation, Key(c.Method(), path, c.Request().URI().QueryArgs().String()))
+		if r, ok := s.get(ctx, key); ok {
+			c.Set(metrics.CacheHeader, resultHit)
+			return s.serve(c, r)
+		}
+
+		c.Set(metrics.CacheHeader, resultMiss)
+		if err := c.Next(); err != nil {
+			return err
+		}
+		switch c.Response().StatusCode() {
+		case http.StatusOK:
+		case http.StatusNotModified:
+			// the handler revalidated the client's copy, which it must keep revalidating
+			s.setExpiry(c, ttl)
+			return nil
+		default:
+			return nil
+		}
+		r := &response{
+			Status:  c.Response().StatusCode(),
+			Body:    c.Response().Body(),
+			Expires: s.now().Add(ttl),
+		}
+		c.Response().Header.VisitAll(func(k, v []byte) {
+			if !uncachedHeaders[string(k)] {
+				r.Headers = append(r.Headers, [2]string{string(k), string(v)})
+			}
+		})
+		if b, err := json.Marshal(r); err != nil {
+			s.log(ctx).Warnf("failed to encode the response to cache: %v", err)
+		} else if err := s.config.Storage.Set(ctx, key, b, ttl); err != nil {
+			s.log(ctx).Warnf("failed to cache the response: %v", err)
+		}
+		s.setExpiry(c, ttl)
+		return nil
+	}
+}
+
+// serve answers c with r, or with 304 when the client has the representation of r, validated like handlers do by
+// its ETag and Last-Modified.
+func (s *Cache) serve(c *fiber.Ctx, r *response) error {
+	for _, h := range r.Headers {
+		c.Set(h[0], h[1])
+	}
+	s.setExpiry(c, r.Expires.Sub(s.now()))
+	// a malformed Last-Modified leaves the zero time, which never validates
+	lastModified, _ := http.ParseTime(c.GetRespHeader(fiber.HeaderLastModified))
+	if fiberutil.NotModified(c, c.GetRespHeader(fiber.HeaderETag), lastModified) {
+		return c.SendStatus(http.StatusNotModified)
+	}
+	c.Status(r.Status)
+	return c.Send(r.Body)
+}
+
+// setExpiry lets clients keep the response for ttl, or revalidate it every time when it carries an ETag, which is
+// cheap and never serves them a response invalidated by a write.
+func (s *Cache) setExpiry(c *fiber.Ctx, ttl time.Duration) {
+	if c.GetRespHeader(fiber.HeaderETag) != "" {
+		s.setCacheControl(c, "no-cache")
+		return
+	}
+	s.setCacheControl(c, fmt.Sprintf("max-age=%d", int(ttl.Seconds())))
+}
+
+// setCacheControl sets the Cache-Control directive of the response, marked private when the cache is.
+func (s *Cache) setCacheControl(c *fiber.Ctx, directive string) {
+	if s.config.Private {
+		directive = "private, " + directive
+	}
+	c.Set(fiber.HeaderCacheControl, directive)
+}
+
+// Invalidate makes the cached responses of paths, whatever their query, unreachable on every instance. Failures
+// are logged: the responses then expire as usual.
+func (s *Cache) Invalidate(paths ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), invalidateTimeout)
+	defer cancel()
+	normalized := make([]string, len(paths))
+	for i, path := range paths {
+		normalized[i] = fiberutil.NormalizePath(path)
+	}
+	paths = normalized
+	s.invalidate(ctx, paths)
+	if s.config.Broadcaster != nil {
+		if err := s.config.Broadcaster.Publish(ctx, paths); err != nil {
+			s.log(ctx).Errorf("failed to send the cache invalidation of %v to the other instances: %v", paths, err)
+		}
+	}
+}
+
+func (s *Cache) invalidate(ctx context.Context, paths []string) {
+	for _, path := range paths {
+		if _, err := s.config.Storage.Incr(ctx, generationKey(path), MaxTTL); err != nil {
+			s.log(ctx).Errorf("failed to invalidate the cached responses of %s: %v", path, err)
+		}
+	}
+}
+
+// Run applies the invalidations of the other instances until ctx is cancelled, when the cache has a Broadcaster.
+func (s *Cache) Run(ctx context.Context) {
+	if s.config.Broadcaster == nil {
+		return
+	}
+	for {
+		err := s.config.Broadcaster.Subscribe(ctx, func(paths []string) {
+			s.invalidate(ctx, paths)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		s.log(ctx).Errorf("lost the cache invalidations of the other instances, subscribing again: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+// Close closes the storage, and the broadcaster when it is an io.Closer.
+func (s *Cache) Close() error {
+	err := s.config.Storage.Close()
+	if closer, ok := s.config.Broadcaster.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
+
+func (s *Cache) generation(ctx context.Context, path string) (uint64, error) {
+	b, err := s.config.Storage.Get(ctx, generationKey(path))
+	if err != nil || b == nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(b), 10, 64)
+}
+
+func (s *Cache) get(ctx context.Context, key string) (*response, bool) {
+	b, err := s.config.Storage.Get(ctx, key)
+	if err != nil {
+		s.log(ctx).Warnf("failed to read the cached response: %v", err)
+		return nil, false
+	}
+	if b == nil {
+		return nil, false
+	}
+	var r response
+	if err := json.Unmarshal(b, &r); err != nil {
+		s.log(ctx).Warnf("failed to decode the cached response: %v", err)
+		return nil, false
+	}
+	if !s.now().Before(r.Expires) {
+		return nil, false
+	}
+	return &r, true
+}
+
+func (s *Cache) log(ctx context.Context) *logrus.Entry {
+	return requestlog.Logger(ctx, s.config.Logger).WithField("component", "cache")
+}
+
+func generationKey(path string) string {
+	return "generation:" + path
+}
+
+func responseKey(generation uint64, key string) string {
+	return "response:" + strconv.FormatUint(generation, 10) + ":" + key
+}
+
+// Key returns the cache key of a request: its method, normalized path and query with the parameters sorted by
+// name, so that the same request written differently shares its cached response.
+func Key(method, path, rawQuery string) string {
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// keeps malformed queries distinct rather than merging them
+		return method + " " + fiberutil.NormalizePath(path) + "?" + rawQuery
+	}
+	return method + " " + fiberutil.NormalizePath(path) + "?" + query.Encode()
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/metrics"
+)
+
+func TestKey(t *testing.T) {
+	same := [][2]string{
+		{"/entries", "filter=a&pageSize=10"},
+		{"/entries/", "pageSize=10&filter=a"},
+		{"/Entries", "pageSize=10&filter=%61"},
+	}
+	if Key(http.MethodGet, "/targets/Prod/tables", "") == Key(http.MethodGet, "/targets/prod/tables", "") {
+		t.Error("requests to targets whose names differ in case share a key")
+	}
+	want := Key(http.MethodGet, same[0][0], same[0][1])
+	for _, r := range same[1:] {
+		if got := Key(http.MethodGet, r[0], r[1]); got != want {
+			t.Errorf("Key(%q, %q) = %q, want %q", r[0], r[1], got, want)
+		}
+	}
+	if Key(http.MethodGet, "/entries", "filter=a") == Key(http.MethodGet, "/entries", "filter=b") {
+		t.Error("requests with different filters share a key")
+	}
+}
+
+// testApp counts the calls of its handlers, whose responses change on every call.
+type testApp struct {
+	*fiber.App
+	cache *Cache
+	calls int
+}
+
+func newTestApp(t *testing.T) *testApp {
+	return newTestAppWith(t, nil, nil)
+}
+
+func newTestAppWith(t *testing.T, storage Storage, broadcaster Broadcaster) *testApp {
+	t.Helper()
+	a := &testApp{App: fiber.New()}
+	a.cache = New(Config{
+		Storage:     storage,
+		Broadcaster: broadcaster,
+		Next:        func(c *fiber.Ctx) bool { return c.Query("refresh") == "true" },
+		TTL: func(c *fiber.Ctx) time.Duration {
+			if c.Path() == "/live" {
+				return 0
+			}
+			return time.Minute
+		},
+	})
+	a.Use(a.cache.Middleware())
+	handler := func(c *fiber.Ctx) error {
+		a.calls++
+		if c.Query("fail") == "true" {
+			return c.SendStatus(http.StatusInternalServerError)
+		}
+		if c.Query("etag") == "true" {
+			c.Set(fiber.HeaderETag, `"v1"`)
+		}
+		if c.Query("modified") == "true" {
+			c.Set(fiber.HeaderLastModified, "Thu, 01 Jun 2023 12:00:00 GMT")
+		}
+		return c.SendString(c.OriginalURL())
+	}
+	a.Get("/entries", handler)
+	a.Get("/entry/:id", handler)
+	a.Get("/live", handler)
+	a.Get("/targets/:name/tables", handler)
+	return a
+}
+
+func (a *testApp) get(t *testing.T, target string, header ...string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := a.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestMiddleware(t *testing.T) {
+	a := newTestApp(t)
+	steps := []struct {
+		target     string
+		wantResult string
+		wantCalls  int
+	}{
+		{"/entries?filter=a", "miss", 1},
+		{"/entries?filter=a", "hit", 1},
+		{"/entries?filter=b", "miss", 2},
+		{"/entries?filter=a&refresh=true", "unreachable", 3},
+		{"/live", "unreachable", 4},
+		{"/entries?fail=true", "miss", 5},
+		{"/entries?fail=true", "miss", 6},
+	}
+	for _, s := range steps {
+		resp := a.get(t, s.target)
+		if got := resp.Header.Get(metrics.CacheHeader); got != s.wantResult {
+			t.Errorf("GET %s: %s = %q, want %q", s.target, metrics.CacheHeader, got, s.wantResult)
+		}
+		if a.calls != s.wantCalls {
+			t.Errorf("GET %s: %d handler calls, want %d", s.target, a.calls, s.wantCalls)
+		}
+	}
+	if got := a.get(t, "/entries?filter=a").Header.Get(fiber.HeaderCacheControl); got != "max-age=59" && got != "max-age=60" {
+		t.Errorf("Cache-Control of a cached response = %q, want max-age of the remaining lifetime", got)
+	}
+	if got := a.get(t, "/live").Header.Get(fiber.HeaderCacheControl); got != "no-store" {
+		t.Errorf("Cache-Control of an uncached response = %q, want no-store", got)
+	}
+}
+
+func TestMiddleware_NormalizedPath(t *testing.T) {
+	a := newTestApp(t)
+	steps := []struct {
+		target     string
+		wantResult string
+		wantCalls  int
+	}{
+		{"/entries?filter=a", "miss", 1},
+		{"/Entries?filter=a", "hit", 1},
+		{"/entries/?filter=a", "hit", 1},
+		{"/targets/prod/tables", "miss", 2},
+		{"/Targets/prod/Tables/", "hit", 2},
+		// target names are case sensitive
+		{"/targets/Prod/tables", "miss", 3},
+	}
+	for _, s := range steps {
+		resp := a.get(t, s.target)
+		if got := resp.Header.Get(metrics.CacheHeader); got != s.wantResult {
+			t.Errorf("GET %s: %s = %q, want %q", s.target, metrics.CacheHeader, got, s.wantResult)
+		}
+		if a.calls != s.wantCalls {
+			t.Errorf("GET %s: %d handler calls, want %d", s.target, a.calls, s.wantCalls)
+		}
+	}
+	a.cache.Invalidate("/Entries/")
+	if got := a.get(t, "/entries?filter=a").Header.Get(metrics.CacheHeader); got != "miss" {
+		t.Errorf("GET /entries after invalidating /Entries/: %q, want miss", got)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	a := newTestApp(t)
+	a.get(t, "/entries?pageSize=1")
+	a.get(t, "/entries?pageSize=2")
+	a.get(t, "/entry/1")
+	a.cache.Invalidate("/entries/")
+	for _, target := range []string{"/entries?pageSize=1", "/entries?pageSize=2"} {
+		if got := a.get(t, target).Header.Get(metrics.CacheHeader); got != "miss" {
+			t.Errorf("GET %s after invalidation: %q, want miss", target, got)
+		}
+	}
+	if got := a.get(t, "/entry/1").Header.Get(metrics.CacheHeader); got != "hit" {
+		t.Errorf("GET /entry/1 after invalidating /entries: %q, want hit", got)
+	}
+}
+
+func TestInvalidate_DuringRequest(t *testing.T) {
+	a := newTestApp(t)
+	a.Get("/racy", func(c *fiber.Ctx) error {
+		// a write completing while the response is built
+		a.cache.Invalidate("/racy")
+		return c.SendString("stale")
+	})
+	a.get(t, "/racy")
+	if got := a.get(t, "/racy").Header.Get(metrics.CacheHeader); got != "miss" {
+		t.Errorf("response built before an invalidation served from the cache: %q", got)
+	}
+}
+
+func TestMiddleware_ETag(t *testing.T) {
+	a := newTestApp(t)
+	a.get(t, "/entry/1?etag=true")
+	resp := a.get(t, "/entry/1?etag=true", fiber.HeaderIfNoneMatch, `W/"v0", "v1"`)
+	if resp.StatusCode != http.StatusNotModified || resp.Header.Get(metrics.CacheHeader) != "hit" {
+		t.Errorf("revalidation = %d %s, want 304 from the cache", resp.StatusCode, resp.Header.Get(metrics.CacheHeader))
+	}
+	if got := resp.Header.Get(fiber.HeaderCacheControl); got != "no-cache" {
+		t.Errorf("Cache-Control with an ETag = %q, want no-cache", got)
+	}
+	resp = a.get(t, "/entry/1?etag=true", fiber.HeaderIfNoneMatch, `"v0"`)
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "/entry/1?etag=true" || resp.Header.Get(fiber.HeaderETag) != `"v1"` {
+		t.Errorf("stale revalidation = %d %q %s, want the cached response", resp.StatusCode, body, resp.Header.Get(fiber.HeaderETag))
+	}
+}
+
+func TestMiddleware_LastModified(t *testing.T) {
+	a := newTestApp(t)
+	a.get(t, "/entry/1?modified=true")
+	resp := a.get(t, "/entry/1?modified=true", fiber.HeaderIfModifiedSince, "Thu, 01 Jun 2023 12:00:00 GMT")
+	if resp.StatusCode != http.StatusNotModified || resp.Header.Get(metrics.CacheHeader) != "hit" {
+		t.Errorf("revalidation = %d %s, want 304 from the cache", resp.StatusCode, resp.Header.Get(metrics.CacheHeader))
+	}
+	resp = a.get(t, "/entry/1?modified=true", fiber.HeaderIfModifiedSince, "Thu, 01 Jun 2023 11:59:59 GMT")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(fiber.HeaderLastModified) != "Thu, 01 Jun 2023 12:00:00 GMT" {
+		t.Errorf("stale revalidation = %d %s, want the cached response", resp.StatusCode, resp.Header.Get(fiber.HeaderLastModified))
+	}
+	// If-None-Match takes precedence over If-Modified-Since
+	a.get(t, "/entry/1?modified=true&etag=true")
+	resp = a.get(t, "/entry/1?modified=true&etag=true", fiber.HeaderIfNoneMatch, `"v0"`,
+		fiber.HeaderIfModifiedSince, "Thu, 01 Jun 2023 12:00:00 GMT")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("revalidation with a stale ETag = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestMiddleware_Private(t *testing.T) {
+	a := newTestApp(t)
+	a.cache.config.Private = true
+	for _, target := range []string{"/entries", "/entries"} {
+		if got := a.get(t, target).Header.Get(fiber.HeaderCacheControl); got != "private, max-age=60" && got != "private, max-age=59" {
+			t.Errorf("Cache-Control of a private response = %q, want private, max-age", got)
+		}
+	}
+	if got := a.get(t, "/entry/1?etag=true").Header.Get(fiber.HeaderCacheControl); got != "private, no-cache" {
+		t.Errorf("Cache-Control of a private response with an ETag = %q, want private, no-cache", got)
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisPrefix namespaces the keys of the cache, so that the Redis server may be shared with other services.
+	redisPrefix = "city-falcon:cache:"
+	// redisChannel carries the invalidations between the instances.
+	redisChannel = "city-falcon:cache-invalidations"
+)
+
+// Redis stores the values on a server speaking the Redis protocol, shared by every instance of the service, and
+// broadcasts the invalidations over its publish/subscribe channels.
+type Redis struct {
+	client *redis.Client
+	// instance identifies the messages of this process
+	instance string
+}
+
+// NewRedis connects to the server at url, e.g. redis://:password@host:6379/0. The server need not be reachable yet.
+func NewRedis(url string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("redis.ParseURL(): %w", err)
+	}
+	return &Redis{client: redis.NewClient(opts), instance: uuid.NewString()}, nil
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := r.client.Get(ctx, redisPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, redisPrefix+key, value, ttl).Err()
+}
+
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (uint64, error) {
+	var incr *redis.IntCmd
+	if _, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, redisPrefix+key)
+		pipe.Expire(ctx, redisPrefix+key, ttl)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return uint64(incr.Val()), nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// invalidation is the message published for each invalidation.
+type invalidation struct {
+	Instance string   `json:"instance"`
+	Paths    []string `json:"paths"`
+}
+
+func (r *Redis) Publish(ctx context.Context, paths []string) error {
+	msg, err := json.Marshal(invalidation{Instance: r.instance, Paths: paths})
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, redisChannel, msg).Err()
+}
+
+// Subscribe misses the messages published while the connection is lost, the caches then expire as usual.
+func (r *Redis) Subscribe(ctx context.Context, fn func(paths []string)) error {
+	sub := r.client.Subscribe(ctx, redisChannel)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var inv invalidation
+			if err := json.Unmarshal([]byte(m.Payload), &inv); err != nil || inv.Instance == r.instance {
+				continue
+			}
+			fn(inv.Paths)
+		}
+	}
+}
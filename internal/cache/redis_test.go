@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/metrics"
+)
+
+func newTestRedis(t *testing.T, server *miniredis.Miniredis) *Redis {
+	t.Helper()
+	r, err := NewRedis("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestRedis_Storage(t *testing.T) {
+	server := miniredis.RunT(t)
+	r := newTestRedis(t, server)
+	ctx := context.Background()
+
+	if v, err := r.Get(ctx, "missing"); v != nil || err != nil {
+		t.Errorf("Get(missing) = %q, %v, want nil", v, err)
+	}
+	if err := r.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.Get(ctx, "k"); string(v) != "v" || err != nil {
+		t.Errorf("Get(k) = %q, %v, want v", v, err)
+	}
+	for want := uint64(1); want <= 2; want++ {
+		if n, err := r.Incr(ctx, "n", time.Minute); n != want || err != nil {
+			t.Errorf("Incr(n) = %d, %v, want %d", n, err, want)
+		}
+	}
+	if !server.Exists(redisPrefix + "k") {
+		t.Errorf("keys %v, want k prefixed with %s", server.Keys(), redisPrefix)
+	}
+	server.FastForward(time.Minute)
+	if v, _ := r.Get(ctx, "k"); v != nil {
+		t.Errorf("Get(k) after its ttl = %q, want nil", v)
+	}
+	if v, _ := r.Get(ctx, "n"); v != nil {
+		t.Errorf("Get(n) after its ttl = %q, want nil", v)
+	}
+}
+
+func TestRedis_SharedStorage(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestAppWith(t, newTestRedis(t, server), nil)
+	b := newTestAppWith(t, newTestRedis(t, server), nil)
+
+	a.get(t, "/entries")
+	if got := b.get(t, "/entries").Header.Get(metrics.CacheHeader); got != "hit" {
+		t.Errorf("response cached by another instance: %q, want hit", got)
+	}
+	a.cache.Invalidate("/entries")
+	if got := b.get(t, "/entries").Header.Get(metrics.CacheHeader); got != "miss" {
+		t.Errorf("response invalidated by another instance: %q, want miss", got)
+	}
+}
+
+func TestRedis_Broadcast(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestAppWith(t, nil, newTestRedis(t, server))
+	b := newTestAppWith(t, nil, newTestRedis(t, server))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.cache.Run(ctx)
+	go b.cache.Run(ctx)
+
+	b.get(t, "/entries")
+	// waits for both subscriptions, which are not ready right away
+	deadline := time.Now().Add(5 * time.Second)
+	for server.PubSubNumSub(redisChannel)[redisChannel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("instances did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	a.cache.Invalidate("/entries")
+	for b.get(t, "/entries").Header.Get(metrics.CacheHeader) != "miss" {
+		if time.Now().After(deadline) {
+			t.Fatal("invalidation not applied by the other instance")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
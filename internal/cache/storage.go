@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Storage holds the cached responses and the counters invalidating them. Implementations must be safe for
+// concurrent use.
+type Storage interface {
+	// Get returns the value of key, nil when there is none or it expired.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set sets the value of key, expiring after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Incr increments the decimal counter at key, 0 when there is none, returns its new value and makes it expire
+	// after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (uint64, error)
+	Close() error
+}
+
+// Broadcaster carries the invalidations between the instances of the service whose caches are not shared.
+type Broadcaster interface {
+	// Publish sends the invalidation of paths to every instance.
+	Publish(ctx context.Context, paths []string) error
+	// Subscribe calls fn with the paths of every invalidation published by the other instances until ctx is
+	// cancelled.
+	Subscribe(ctx context.Context, fn func(paths []string)) error
+}
+
+// sweepInterval is how often the memory storage removes the expired values.
+const sweepInterval = time.Minute
+
+type memoryValue struct {
+	value   []byte
+	expires time.Time
+}
+
+// Memory stores the values in the memory of the process.
+type Memory struct {
+	now       func() time.Time
+	mu        sync.Mutex
+	values    map[string]memoryValue
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{now: time.Now, values: map[string]memoryValue{}}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok || !m.now().Before(v.expires) {
+		return nil, nil
+	}
+	return v.value, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, value, ttl)
+	return nil
+}
+
+func (m *Memory) Incr(_ context.Context, key string, ttl time.Duration) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n uint64
+	if v, ok := m.values[key]; ok && m.now().Before(v.expires) {
+		var err error
+		if n, err = strconv.ParseUint(string(v.value), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	n++
+	m.set(key, []byte(strconv.FormatUint(n, 10)), ttl)
+	return n, nil
+}
+
+func (m *Memory) set(key string, value []byte, ttl time.Duration) {
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.lastSweep = now
+		for k, v := range m.values {
+			if !now.Before(v.expires) {
+				delete(m.values, k)
+			}
+		}
+	}
+	m.values[key] = memoryValue{value: value, expires: now.Add(ttl)}
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
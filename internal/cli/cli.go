@@ -0,0 +1,204 @@
+// Package cli implements the commands of the service that read or change its data from the command line.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/google/uuid"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+const EntriesUsage = "usage: entries list [flags] | get [-show-deleted] <id> | create [json] | delete <id>"
+
+// Entries runs the entries command, printing the entries as JSON to out. open connects to the repository once args
+// are known to be valid, the repository is closed before Entries returns.
+func Entries(ctx context.Context, args []string, out io.Writer, open func() (dataprovider.Provider, error)) error {
+	if len(args) == 0 {
+		return errors.New(EntriesUsage)
+	}
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("entries "+command, flag.ContinueOnError)
+	var req model.ListEntriesRequest
+	var showDeleted bool
+	switch command {
+	case "list":
+		flags.StringVar(&req.Filter, "filter", "", "filter expression")
+		flags.StringVar(&req.OrderBy, "order-by", "create_time", "ordering")
+		flags.IntVar(&req.PageSize, "page-size", 100, "maximum number of entries")
+		flags.IntVar(&req.PageOffset, "page-offset", 0, "number of entries skipped")
+	case "get":
+		flags.BoolVar(&showDeleted, "show-deleted", false, "print the entry even if it is deleted")
+	case "create", "delete":
+	default:
+		return fmt.Errorf("unknown entries command %q: %s", command, EntriesUsage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var id uuid.UUID
+	switch command {
+	case "get", "delete":
+		if flags.NArg() != 1 {
+			return errors.New(EntriesUsage)
+		}
+		var err error
+		if id, err = uuid.Parse(flags.Arg(0)); err != nil {
+			return fmt.Errorf("invalid id %q: %w", flags.Arg(0), err)
+		}
+	}
+	entry := model.Entry{}
+	if command == "create" && flags.NArg() > 0 {
+		if err := json.Unmarshal([]byte(flags.Arg(0)), &entry); err != nil {
+			return fmt.Errorf("invalid entry: %w", err)
+		}
+	}
+
+	repo, err := open()
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	var resp interface{}
+	switch command {
+	case "list":
+		entries, err := repo.ListEntries(ctx, req)
+		if err != nil {
+			return err
+		}
+		resp = model.ListEntriesResponse{Entries: entries}
+	case "get":
+		entry, err := repo.GetByID(ctx, id, showDeleted, func(query *orm.Query) {
+			query.WherePK()
+		})
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return fmt.Errorf("entry %s not found", id)
+		}
+		resp = entry
+	case "create":
+		entry.ID = uuid.New()
+		if resp, err = repo.Create(ctx, &entry); err != nil {
+			return err
+		}
+	case "delete":
+		deleted, err := repo.Delete(ctx, &model.Entry{ID: id}, nil)
+		if err != nil {
+			return err
+		}
+		if deleted == nil {
+			return fmt.Errorf("entry %s not found", id)
+		}
+		resp = deleted
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(resp)
+}
+
+// SlowQueries runs the slow-queries command, printing the queries running on the selected targets as a table to
+// out and the targets that failed to errOut. With -watch the table is refreshed every interval until ctx is done.
+// open returns the targets and the redactor of their query text once args are known to be valid.
+func SlowQueries(ctx context.Context, args []string, out, errOut io.Writer, open func() (*targets.Registry, *redact.Redactor, error)) error {
+	flags := flag.NewFlagSet("slow-queries", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	req := model.SlowQueriesRequest{}
+	flags.StringVar(&req.Filter, "filter", "", "filter expression")
+	flags.StringVar(&req.OrderBy, "order-by", "duration_ms DESC", "ordering")
+	flags.IntVar(&req.PageSize, "limit", 20, "maximum number of queries per target")
+	target := flags.String("target", targets.Default, `target to query, "*" for all`)
+	raw := flags.Bool("raw", false, "print the query text unredacted")
+	watch := flags.Bool("watch", false, "refresh the table until interrupted")
+	interval := flags.Duration("interval", 2*time.Second, "refresh interval of -watch")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid -interval %s: must be positive", *interval)
+	}
+
+	registry, redactor, err := open()
+	if err != nil {
+		return err
+	}
+	selected, err := registry.Select(*target)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		records, failed := targets.FanOut(ctx, selected, func(ctx context.Context, t *targets.Target) ([]*model.SlowQueryRecord, error) {
+			records, err := t.Monitor.SlowQuery(ctx, req)
+			for _, r := range records {
+				r.Target = t.Name
+			}
+			return records, err
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !*raw {
+			for _, r := range records {
+				r.Query = redactor.Redact(r.Query)
+			}
+		}
+		if *watch {
+			// clears the terminal before redrawing the table
+			fmt.Fprint(out, "\033[H\033[2J")
+			fmt.Fprintf(out, "%s, every %s\n\n", time.Now().Format(time.RFC3339), *interval)
+		}
+		if err := printSlowQueries(out, records); err != nil {
+			return err
+		}
+		for name, err := range failed {
+			fmt.Fprintf(errOut, "target %s failed: %v\n", name, err)
+		}
+		if !*watch {
+			if len(failed) > 0 && len(failed) == len(selected) {
+				return errors.New("no target answered")
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// printSlowQueries writes records as a table, each query on a single line.
+func printSlowQueries(w io.Writer, records []*model.SlowQueryRecord) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tPID\tDURATION\tSTATE\tUSER\tDATABASE\tQUERY")
+	for _, r := range records {
+		query := strings.Join(strings.Fields(r.Query), " ")
+		if len(query) > 100 {
+			query = query[:97] + "..."
+		}
+		duration := (time.Duration(r.DurationMS) * time.Millisecond).String()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Target, r.PID, duration, r.State, r.UserName, r.DatabaseName, query)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+// fakeProvider stores entries in memory and records the list request it gets.
+type fakeProvider struct {
+	dataprovider.Provider
+	entries map[uuid.UUID]*model.Entry
+	listReq model.ListEntriesRequest
+	closed  bool
+}
+
+func (f *fakeProvider) ListEntries(ctx context.Context, req model.ListEntriesRequest) ([]*model.Entry, error) {
+	f.listReq = req
+	var entries []*model.Entry
+	for _, e := range f.entries {
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (f *fakeProvider) GetByID(ctx context.Context, id uuid.UUID, showDeleted bool, queryHook dataprovider.QueryHook) (*model.Entry, error) {
+	e := f.entries[id]
+	if e == nil || e.DeleteTime != nil && !showDeleted {
+		return nil, nil
+	}
+	return e, nil
+}
+
+func (f *fakeProvider) Create(ctx context.Context, resource *model.Entry) (*model.Entry, error) {
+	f.entries[resource.ID] = resource
+	return resource, nil
+}
+
+func (f *fakeProvider) Delete(ctx context.Context, resource *model.Entry, queryHook dataprovider.QueryHook) (*model.Entry, error) {
+	e := f.entries[resource.ID]
+	delete(f.entries, resource.ID)
+	return e, nil
+}
+
+func (f *fakeProvider) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestEntries(t *testing.T) {
+	id := uuid.MustParse("6e9412ec-34eb-4c17-91d4-d5591b8c1190")
+	deleted := time.Date(2023, 7, 22, 4, 26, 40, 0, time.UTC)
+	run := func(repo *fakeProvider, args ...string) (string, bool, error) {
+		var out bytes.Buffer
+		opened := false
+		err := Entries(context.Background(), args, &out, func() (dataprovider.Provider, error) {
+			opened = true
+			return repo, nil
+		})
+		return out.String(), opened, err
+	}
+	newRepo := func() *fakeProvider {
+		return &fakeProvider{entries: map[uuid.UUID]*model.Entry{id: {ID: id, Version: 1, DeleteTime: &deleted}}}
+	}
+
+	t.Run("list", func(t *testing.T) {
+		repo := newRepo()
+		out, _, err := run(repo, "list", "-filter", "version = 1", "-page-size", "5", "-page-offset", "10")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := model.ListEntriesRequest{ListParams: model.ListParams{PageSize: 5, PageOffset: 10, OrderBy: "create_time", Filter: "version = 1"}}
+		if repo.listReq != want {
+			t.Errorf("ListEntries() request = %+v, want %+v", repo.listReq, want)
+		}
+		var resp model.ListEntriesResponse
+		if err := json.Unmarshal([]byte(out), &resp); err != nil || len(resp.Entries) != 1 || resp.Entries[0].ID != id {
+			t.Errorf("list printed %s, want the entry", out)
+		}
+		if !repo.closed {
+			t.Error("the repository was not closed")
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		if _, _, err := run(newRepo(), "get", id.String()); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("get of a deleted entry: %v, want not found", err)
+		}
+		out, _, err := run(newRepo(), "get", "-show-deleted", id.String())
+		var e model.Entry
+		if err != nil || json.Unmarshal([]byte(out), &e) != nil || e.Version != 1 {
+			t.Errorf("get -show-deleted printed %s, %v, want the entry", out, err)
+		}
+	})
+
+	t.Run("create and delete", func(t *testing.T) {
+		repo := newRepo()
+		out, _, err := run(repo, "create", `{"Version":2}`)
+		var created model.Entry
+		if err != nil || json.Unmarshal([]byte(out), &created) != nil || created.Version != 2 || created.ID == uuid.Nil {
+			t.Fatalf("create printed %s, %v, want the entry with a new id", out, err)
+		}
+		if _, _, err := run(repo, "delete", created.ID.String()); err != nil || repo.entries[created.ID] != nil {
+			t.Errorf("delete: %v, want the entry deleted", err)
+		}
+		if _, _, err := run(repo, "delete", created.ID.String()); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("delete of a missing entry: %v, want not found", err)
+		}
+	})
+
+	for _, args := range [][]string{
+		nil,
+		{"update"},
+		{"get"},
+		{"get", "not-a-uuid"},
+		{"delete", id.String(), id.String()},
+		{"create", "{"},
+		{"list", "-page-size", "many"},
+	} {
+		if _, opened, err := run(newRepo(), args...); err == nil || opened {
+			t.Errorf("entries %q: error %v, opened the repository %v, want an error before connecting", args, err, opened)
+		}
+	}
+}
+
+// fakeMonitor answers its records, or err, counting the calls.
+type fakeMonitor struct {
+	dataprovider.Monitor
+	records []*model.SlowQueryRecord
+	err     error
+	calls   atomic.Int32
+	req     model.SlowQueriesRequest
+}
+
+func (f *fakeMonitor) SlowQuery(ctx context.Context, req model.SlowQueriesRequest) ([]*model.SlowQueryRecord, error) {
+	f.calls.Add(1)
+	f.req = req
+	var records []*model.SlowQueryRecord
+	for _, r := range f.records {
+		r := *r
+		records = append(records, &r)
+	}
+	return records, f.err
+}
+
+func TestSlowQueries(t *testing.T) {
+	redactor, err := redact.New(redact.ModeLiterals, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := &fakeMonitor{records: []*model.SlowQueryRecord{
+		{PID: "42", DurationMS: 61000, State: "active", UserName: "app", DatabaseName: "orders", Query: "SELECT *\n  FROM orders WHERE id = 7"},
+	}}
+	down := &fakeMonitor{err: errors.New("connection refused")}
+	registry, err := targets.NewRegistry(&targets.Target{Name: targets.Default, Monitor: primary}, &targets.Target{Name: "replica", Monitor: down})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(ctx context.Context, args ...string) (string, string, bool, error) {
+		var out, errOut bytes.Buffer
+		opened := false
+		err := SlowQueries(ctx, args, &out, &errOut, func() (*targets.Registry, *redact.Redactor, error) {
+			opened = true
+			return registry, redactor, nil
+		})
+		return out.String(), errOut.String(), opened, err
+	}
+
+	out, _, _, err := run(context.Background(), "-filter", "duration_ms > 1000", "-limit", "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary.req.Filter != "duration_ms > 1000" || primary.req.PageSize != 5 || primary.req.OrderBy != "duration_ms DESC" {
+		t.Errorf("SlowQuery() request = %+v", primary.req)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "TARGET") || strings.Join(strings.Fields(lines[1]), " ") != "default 42 1m1s active app orders SELECT * FROM orders WHERE id = ?" {
+		t.Errorf("printed\n%s\nwant the redacted query of the default target on one line", out)
+	}
+	if out, _, _, _ := run(context.Background(), "-raw"); !strings.Contains(out, "id = 7") {
+		t.Errorf("-raw printed\n%s\nwant the query text unredacted", out)
+	}
+
+	out, errOut, _, err := run(context.Background(), "-target", targets.All)
+	if err != nil || !strings.Contains(out, "default") || !strings.Contains(errOut, "target replica failed: connection refused") {
+		t.Errorf("-target * printed\n%s%s%v\nwant the queries of default and the failure of replica", out, errOut, err)
+	}
+	if _, _, _, err := run(context.Background(), "-target", "replica"); err == nil {
+		t.Error("-target replica succeeded, want an error as no target answered")
+	}
+
+	// -watch redraws the table until the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	primary.calls.Store(0)
+	go func() {
+		for primary.calls.Load() < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	out, _, _, err = run(ctx, "-watch", "-interval", "1ms")
+	if err != nil || strings.Count(out, "\033[H\033[2J") < 2 {
+		t.Errorf("-watch: %v, printed\n%q\nwant the table redrawn until cancelled", err, out)
+	}
+
+	for _, args := range [][]string{{"-limit", "many"}, {"extra"}, {"-watch", "-interval", "0"}, {"-interval", "-1s"}} {
+		if _, _, opened, err := run(context.Background(), args...); err == nil || opened {
+			t.Errorf("slow-queries %q: error %v, opened the targets %v, want an error before connecting", args, err, opened)
+		}
+	}
+}
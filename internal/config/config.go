@@ -0,0 +1,347 @@
+// Package config loads the configuration of the service from, in increasing order of precedence, its defaults, a
+// YAML or JSON file, the environment and command-line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/alerting"
+	"github.com/rahul2393/city-falcon-assignment/internal/auth"
+	"github.com/rahul2393/city-falcon-assignment/internal/cache"
+	"github.com/rahul2393/city-falcon-assignment/pkg/listing"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+// Config is the configuration of every command. Each field is set by the environment variable named by its env
+// tag, by the file key and by the flag of the same name in lower case, e.g. db_url and -db-url for DB_URL. Fields
+// tagged secret are masked when printed, those tagged reload may change while the server runs.
+type Config struct {
+	// DBURL is the URL of the service's database.
+	DBURL string `env:"DB_URL" secret:"url"`
+	// DBPoolSize is the maximum number of connections to each database, 0 for 10 per CPU.
+	DBPoolSize int `env:"DB_POOL_SIZE"`
+	// DBMinIdleConns is the number of idle connections kept open to each database.
+	DBMinIdleConns int `env:"DB_MIN_IDLE_CONNS"`
+	// DBDialTimeout, DBReadTimeout and DBWriteTimeout bound the socket operations, 0 for no read or write timeout.
+	DBDialTimeout  Duration `env:"DB_DIAL_TIMEOUT"`
+	DBReadTimeout  Duration `env:"DB_READ_TIMEOUT"`
+	DBWriteTimeout Duration `env:"DB_WRITE_TIMEOUT"`
+	// DBMaxRetries is the number of retries of transactions failing with a transient error, waiting from
+	// DBMinRetryBackoff up to DBMaxRetryBackoff in between.
+	DBMaxRetries      int      `env:"DB_MAX_RETRIES"`
+	DBMinRetryBackoff Duration `env:"DB_MIN_RETRY_BACKOFF"`
+	DBMaxRetryBackoff Duration `env:"DB_MAX_RETRY_BACKOFF"`
+	// DBReplicaURLs are the URLs of read replicas of the database serving the entry reads, checked for health
+	// every DBReplicaCheckInterval.
+	DBReplicaURLs          []string `env:"DB_REPLICA_URLS" secret:"url"`
+	DBReplicaCheckInterval Duration `env:"DB_REPLICA_CHECK_INTERVAL"`
+	// DBReadAfterWriteWindow is how long after writing an entry the entry reads of the same caller keep going to the
+	// primary, 0 for none.
+	DBReadAfterWriteWindow Duration `env:"DB_READ_AFTER_WRITE_WINDOW"`
+	// MigrateOnStart applies the pending schema migrations at startup, otherwise the migrate command must be run.
+	MigrateOnStart bool `env:"MIGRATE_ON_START"`
+	// ListenAddressHTTP is the port the server listens on.
+	ListenAddressHTTP string `env:"LISTEN_ADDRESS_HTTP"`
+	// ShutdownTimeout bounds the wait for requests in progress on shutdown.
+	ShutdownTimeout Duration `env:"SHUTDOWN_TIMEOUT"`
+	// RequestTimeout bounds the handling of each request, 0 for no timeout, unless RouteTimeouts, which maps the
+	// path templates of routes, e.g. /entry/:id, to their timeouts, sets another one for the route of the request.
+	RequestTimeout Duration            `env:"REQUEST_TIMEOUT" reload:"true"`
+	RouteTimeouts  map[string]Duration `env:"ROUTE_TIMEOUTS" reload:"true"`
+	// CacheTTL is the lifetime of the cached responses, 0 disables the cache, unless CacheRouteTTLs, which maps the
+	// path templates of routes to lifetimes, sets another one for the route of the request. The live activity of
+	// /slow-queries is not cached unless CacheRouteTTLs sets the lifetime of its routes: the sources are merged over
+	// these defaults.
+	CacheTTL       Duration            `env:"CACHE_TTL" reload:"true"`
+	CacheRouteTTLs map[string]Duration `env:"CACHE_ROUTE_TTLS" reload:"true"`
+	// CacheStorage is "memory" to cache the responses in each instance or "redis" to share them between instances
+	// on the Redis server at CacheRedisURL. With memory storage and CacheRedisURL set, the instances send each other
+	// their invalidations through the server.
+	CacheStorage  string `env:"CACHE_STORAGE"`
+	CacheRedisURL string `env:"CACHE_REDIS_URL" secret:"url"`
+	// MaxPageSize caps the page size of the list APIs.
+	MaxPageSize int `env:"MAX_PAGE_SIZE"`
+	// Version is the version of the service reported by /debug/status and in the logs.
+	Version string `env:"APP_VERSION"`
+	// Host names the host in the logs.
+	Host string `env:"HOST"`
+
+	// LogLevel is the minimum level of the logged messages.
+	LogLevel string `env:"LOG_LEVEL" reload:"true"`
+	// LogJSON logs JSON objects instead of text.
+	LogJSON bool `env:"LOG_JSON"`
+	// LogQuery logs every query of the service at debug level.
+	LogQuery bool `env:"LOG_QUERY" reload:"true"`
+	// QueryLogThreshold logs the service's own queries running at least as long at warn level, 0 disables it.
+	QueryLogThreshold Duration `env:"QUERY_LOG_THRESHOLD" reload:"true"`
+	// QueryLogSampleRate is the fraction, from 0 to 1, of the other queries logged at debug level.
+	QueryLogSampleRate float64 `env:"QUERY_LOG_SAMPLE_RATE" reload:"true"`
+
+	// SampleInterval enables the slow query sampler when not 0.
+	SampleInterval Duration `env:"SLOW_QUERY_SAMPLE_INTERVAL"`
+	// SampleThreshold is the minimum running time of a query recorded by the sampler.
+	SampleThreshold Duration `env:"SLOW_QUERY_THRESHOLD" reload:"true"`
+	// SampleRetention is how long the sampler keeps the slow query history after a query was last seen, 0 keeps it
+	// forever.
+	SampleRetention Duration `env:"SLOW_QUERY_RETENTION" reload:"true"`
+
+	// RedactMode is one of "literals", "rules" or "none".
+	RedactMode  redact.Mode   `env:"REDACT_MODE"`
+	RedactRules []redact.Rule `env:"REDACT_RULES"`
+	// AdminToken, when set, allows requests carrying it in the X-Admin-Token header to ask for raw query text while
+	// authentication is disabled. Once AuthAPIKeys or AuthJWKS enable it, the admin role does.
+	AdminToken string `env:"ADMIN_TOKEN" secret:"true"`
+
+	// AuthAPIKeys are the API keys accepted in the X-API-Key header, by their hashes.
+	AuthAPIKeys []auth.APIKey `env:"AUTH_API_KEYS" secret:"true"`
+	// AuthJWKS is the path or http(s) URL of the JSON Web Key Set verifying the JWT bearer tokens, read again every
+	// AuthJWKSRefreshInterval. The tokens must match AuthJWTIssuer and AuthJWTAudience when set and carry the roles
+	// of their subject in the AuthJWTRolesClaim claim.
+	AuthJWKS                string   `env:"AUTH_JWKS" secret:"url"`
+	AuthJWKSRefreshInterval Duration `env:"AUTH_JWKS_REFRESH_INTERVAL"`
+	AuthJWTIssuer           string   `env:"AUTH_JWT_ISSUER"`
+	AuthJWTAudience         string   `env:"AUTH_JWT_AUDIENCE"`
+	AuthJWTRolesClaim       string   `env:"AUTH_JWT_ROLES_CLAIM"`
+	// AuthPublicPaths are the path templates of the routes served without authentication when it is enabled by
+	// AuthAPIKeys or AuthJWKS.
+	AuthPublicPaths []string `env:"AUTH_PUBLIC_PATHS"`
+
+	// MonitorTargets maps target names to the URLs of additional servers to monitor.
+	MonitorTargets map[string]string `env:"MONITOR_TARGETS" secret:"url"`
+	// TracesExporter is "otlp" to export traces, configured by the standard OTEL_* variables, or "none".
+	TracesExporter string `env:"OTEL_TRACES_EXPORTER"`
+
+	// AlertRules are evaluated every AlertInterval.
+	AlertRules    []alerting.Rule `env:"ALERT_RULES" reload:"true"`
+	AlertInterval Duration        `env:"ALERT_INTERVAL"`
+	// AlertWebhookURLs receive the alerts.
+	AlertWebhookURLs []string `env:"ALERT_WEBHOOK_URLS" secret:"url"`
+
+	// file is the path of the config file the configuration was loaded from, if any.
+	file string
+}
+
+// File returns the path of the config file the configuration was loaded from, empty if none was.
+func (c *Config) File() string {
+	return c.file
+}
+
+// Default returns the configuration used for the settings no source sets.
+func Default() *Config {
+	return &Config{
+		DBDialTimeout:           Duration(5 * time.Second),
+		DBMaxRetries:            2,
+		DBMinRetryBackoff:       Duration(250 * time.Millisecond),
+		DBMaxRetryBackoff:       Duration(4 * time.Second),
+		DBReplicaCheckInterval:  Duration(5 * time.Second),
+		DBReadAfterWriteWindow:  Duration(5 * time.Second),
+		MigrateOnStart:          true,
+		ShutdownTimeout:         Duration(30 * time.Second),
+		RequestTimeout:          Duration(30 * time.Second),
+		CacheTTL:                Duration(30 * time.Second),
+		CacheRouteTTLs:          map[string]Duration{"/slow-queries": 0, "/targets/:name/slow-queries": 0},
+		CacheStorage:            "memory",
+		MaxPageSize:             100,
+		LogLevel:                "trace",
+		SampleThreshold:         Duration(time.Second),
+		SampleRetention:         Duration(7 * 24 * time.Hour),
+		RedactMode:              redact.ModeLiterals,
+		AuthJWKSRefreshInterval: Duration(5 * time.Minute),
+		AuthJWTRolesClaim:       "roles",
+		AuthPublicPaths:         []string{"/healthz", "/readyz", "/metrics"},
+		TracesExporter:          "none",
+		AlertInterval:           Duration(30 * time.Second),
+	}
+}
+
+// Duration is a time.Duration written like "1m30s" in every source.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Validate checks every setting and reports all the invalid ones at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(env, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", env, fmt.Sprintf(format, args...)))
+	}
+	if c.DBURL != "" {
+		if _, err := pg.ParseURL(c.DBURL); err != nil {
+			invalid("DB_URL", "%v", err)
+		}
+	}
+	if c.DBPoolSize < 0 {
+		invalid("DB_POOL_SIZE", "must not be negative")
+	}
+	if c.DBMinIdleConns < 0 {
+		invalid("DB_MIN_IDLE_CONNS", "must not be negative")
+	}
+	if c.DBDialTimeout <= 0 {
+		invalid("DB_DIAL_TIMEOUT", "must be positive")
+	}
+	if c.DBReadTimeout < 0 {
+		invalid("DB_READ_TIMEOUT", "must not be negative")
+	}
+	if c.DBWriteTimeout < 0 {
+		invalid("DB_WRITE_TIMEOUT", "must not be negative")
+	}
+	if c.DBMaxRetries < 0 {
+		invalid("DB_MAX_RETRIES", "must not be negative")
+	}
+	if c.DBMinRetryBackoff <= 0 || c.DBMinRetryBackoff > c.DBMaxRetryBackoff {
+		invalid("DB_MIN_RETRY_BACKOFF", "must be positive and at most DB_MAX_RETRY_BACKOFF")
+	}
+	for _, u := range c.DBReplicaURLs {
+		if _, err := pg.ParseURL(u); err != nil {
+			invalid("DB_REPLICA_URLS", "replica %s: %v", maskURL(u), err)
+		}
+	}
+	if c.DBReplicaCheckInterval <= 0 {
+		invalid("DB_REPLICA_CHECK_INTERVAL", "must be positive")
+	}
+	if c.DBReadAfterWriteWindow < 0 {
+		invalid("DB_READ_AFTER_WRITE_WINDOW", "must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		invalid("SHUTDOWN_TIMEOUT", "must be positive")
+	}
+	if c.RequestTimeout < 0 {
+		invalid("REQUEST_TIMEOUT", "must not be negative")
+	}
+	for route, d := range c.RouteTimeouts {
+		if !strings.HasPrefix(route, "/") {
+			invalid("ROUTE_TIMEOUTS", "route %q must start with /", route)
+		}
+		if d < 0 {
+			invalid("ROUTE_TIMEOUTS", "route %q: timeout must not be negative", route)
+		}
+	}
+	if c.CacheTTL < 0 || time.Duration(c.CacheTTL) > cache.MaxTTL {
+		invalid("CACHE_TTL", "must be between 0 and %s", cache.MaxTTL)
+	}
+	for route, d := range c.CacheRouteTTLs {
+		if !strings.HasPrefix(route, "/") {
+			invalid("CACHE_ROUTE_TTLS", "route %q must start with /", route)
+		}
+		if d < 0 || time.Duration(d) > cache.MaxTTL {
+			invalid("CACHE_ROUTE_TTLS", "route %q: lifetime must be between 0 and %s", route, cache.MaxTTL)
+		}
+	}
+	switch c.CacheStorage {
+	case "memory":
+	case "redis":
+		if c.CacheRedisURL == "" {
+			invalid("CACHE_STORAGE", "redis storage requires CACHE_REDIS_URL")
+		}
+	default:
+		invalid("CACHE_STORAGE", "want memory or redis, got %q", c.CacheStorage)
+	}
+	if c.CacheRedisURL != "" {
+		if u, err := url.Parse(c.CacheRedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			invalid("CACHE_REDIS_URL", "want a redis or rediss URL, got %q", maskURL(c.CacheRedisURL))
+		}
+	}
+	if c.MaxPageSize < 1 {
+		invalid("MAX_PAGE_SIZE", "must be positive")
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		invalid("LOG_LEVEL", "%v", err)
+	}
+	if c.QueryLogThreshold < 0 {
+		invalid("QUERY_LOG_THRESHOLD", "must not be negative")
+	}
+	if c.QueryLogSampleRate < 0 || c.QueryLogSampleRate > 1 {
+		invalid("QUERY_LOG_SAMPLE_RATE", "must be between 0 and 1, got %v", c.QueryLogSampleRate)
+	}
+	if c.SampleInterval < 0 {
+		invalid("SLOW_QUERY_SAMPLE_INTERVAL", "must not be negative")
+	}
+	if c.SampleThreshold <= 0 {
+		invalid("SLOW_QUERY_THRESHOLD", "must be positive")
+	}
+	if c.SampleRetention < 0 {
+		invalid("SLOW_QUERY_RETENTION", "must not be negative")
+	}
+	if _, err := redact.New(c.RedactMode, c.RedactRules); err != nil {
+		invalid("REDACT_RULES", "%v", err)
+	}
+	if _, err := auth.NewAPIKeys(c.AuthAPIKeys); err != nil {
+		invalid("AUTH_API_KEYS", "%v", err)
+	}
+	if c.AuthJWKS == "" && (c.AuthJWTIssuer != "" || c.AuthJWTAudience != "") {
+		invalid("AUTH_JWKS", "required by AUTH_JWT_ISSUER and AUTH_JWT_AUDIENCE")
+	}
+	if c.AuthJWKSRefreshInterval < 0 {
+		invalid("AUTH_JWKS_REFRESH_INTERVAL", "must not be negative")
+	}
+	if c.AdminToken != "" && (len(c.AuthAPIKeys) > 0 || c.AuthJWKS != "") {
+		invalid("ADMIN_TOKEN", "not used with AUTH_API_KEYS or AUTH_JWKS, give the admin role to a key or token instead")
+	}
+	for _, path := range c.AuthPublicPaths {
+		if !strings.HasPrefix(path, "/") {
+			invalid("AUTH_PUBLIC_PATHS", "path %q must start with /", path)
+		}
+	}
+	for name, u := range c.MonitorTargets {
+		if _, err := pg.ParseURL(u); err != nil {
+			invalid("MONITOR_TARGETS", "target %q: %v", name, err)
+		}
+	}
+	switch c.TracesExporter {
+	case "", "none", "otlp":
+	default:
+		invalid("OTEL_TRACES_EXPORTER", "unsupported traces exporter %q", c.TracesExporter)
+	}
+	for i, rule := range c.AlertRules {
+		if rule.Name == "" {
+			invalid("ALERT_RULES", "rule #%d has no name", i+1)
+		}
+		if _, err := listing.Parse(rule.Filter); err != nil {
+			invalid("ALERT_RULES", "rule %q: %v", rule.Name, err)
+		}
+	}
+	if c.AlertInterval <= 0 {
+		invalid("ALERT_INTERVAL", "must be positive")
+	}
+	for _, v := range c.AlertWebhookURLs {
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			invalid("ALERT_WEBHOOK_URLS", "want an http or https URL, got %q", maskURL(v))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Require fails unless every setting named by its environment variable in envs is set, reporting all the missing
+// ones at once.
+func (c *Config) Require(envs ...string) error {
+	var errs []error
+	for _, env := range envs {
+		f, ok := fieldByEnv(env)
+		if !ok {
+			panic("config: unknown setting " + env)
+		}
+		if reflect.ValueOf(c).Elem().FieldByIndex(f.Index).IsZero() {
+			errs = append(errs, fmt.Errorf("%s is required (file key %s, flag -%s)", env, f.key, f.flag))
+		}
+	}
+	return errors.Join(errs...)
+}
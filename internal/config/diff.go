@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Change is a setting that differs between two configurations. Old and New are JSON, with the secrets masked.
+type Change struct {
+	Env        string
+	Old, New   string
+	Reloadable bool
+}
+
+// Diff lists the settings of next that differ from old.
+func Diff(old, next *Config) []Change {
+	var changes []Change
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(next).Elem()
+	for _, f := range fields {
+		o, n := ov.FieldByIndex(f.Index).Interface(), nv.FieldByIndex(f.Index).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, Change{
+			Env:        f.env,
+			Old:        marshalMasked(f, o),
+			New:        marshalMasked(f, n),
+			Reloadable: f.reload,
+		})
+	}
+	return changes
+}
+
+func marshalMasked(f field, value interface{}) string {
+	b, err := json.Marshal(masked(f, value))
+	if err != nil {
+		return mask
+	}
+	return string(b)
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// FileEnv names the environment variable holding the path of the config file, which the -config flag overrides.
+const FileEnv = "CONFIG_FILE"
+
+// field describes a setting of Config.
+type field struct {
+	reflect.StructField
+	env    string
+	key    string
+	flag   string
+	secret string
+	reload bool
+}
+
+var fields = func() []field {
+	t := reflect.TypeOf(Config{})
+	list := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		env := f.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		key := strings.ToLower(env)
+		list = append(list, field{
+			StructField: f,
+			env:         env,
+			key:         key,
+			flag:        strings.ReplaceAll(key, "_", "-"),
+			secret:      f.Tag.Get("secret"),
+			reload:      f.Tag.Get("reload") == "true",
+		})
+	}
+	return list
+}()
+
+func fieldByEnv(env string) (field, bool) {
+	for _, f := range fields {
+		if f.env == env {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// Load builds the configuration from the defaults, the config file, the environment read with getenv and the
+// flags at the start of args, each source overriding the previous ones, and validates it. It returns the
+// arguments following the flags. Every invalid setting is reported at once.
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	path := flags.String("config", getenv(FileEnv), "YAML or JSON config file, also set by "+FileEnv)
+	type flagValue struct {
+		field field
+		value string
+	}
+	var flagValues []flagValue
+	for _, f := range fields {
+		f := f
+		flags.Func(f.flag, "overrides "+f.env, func(v string) error {
+			flagValues = append(flagValues, flagValue{f, v})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	c := Default()
+	v := reflect.ValueOf(c).Elem()
+	var errs []error
+	c.file = *path
+	if *path != "" {
+		if err := loadFile(c, *path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range fields {
+		if s := getenv(f.env); s != "" {
+			if err := setString(v.FieldByIndex(f.Index), s); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+		}
+	}
+	for _, fv := range flagValues {
+		if err := setString(v.FieldByIndex(fv.field.Index), fv.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", fv.field.flag, err))
+		}
+	}
+	mergeDefaultRouteTTLs(c)
+	if len(errs) == 0 {
+		if err := c.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return c, flags.Args(), nil
+}
+
+// mergeDefaultRouteTTLs adds the default lifetimes of the routes CacheRouteTTLs lacks once every source replaced it,
+// so that the live activity stays uncached unless a source sets the lifetime of its routes.
+func mergeDefaultRouteTTLs(c *Config) {
+	for route, ttl := range Default().CacheRouteTTLs {
+		if _, ok := c.CacheRouteTTLs[route]; ok {
+			continue
+		}
+		if c.CacheRouteTTLs == nil {
+			c.CacheRouteTTLs = map[string]Duration{}
+		}
+		c.CacheRouteTTLs[route] = ttl
+	}
+}
+
+// loadFile sets the keys of the YAML or JSON file at path, chosen by its extension, on c.
+func loadFile(c *Config, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		if b, err = yaml.YAMLToJSON(b); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config file extension %q, want .yaml, .yml or .json", path, ext)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(b, &values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	v := reflect.ValueOf(c).Elem()
+	var errs []error
+	for _, f := range fields {
+		raw, ok := values[f.key]
+		if !ok {
+			continue
+		}
+		delete(values, f.key)
+		if err := json.Unmarshal(raw, v.FieldByIndex(f.Index).Addr().Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, f.key, err))
+		}
+	}
+	for key := range values {
+		errs = append(errs, fmt.Errorf("%s: unknown key %q", path, key))
+	}
+	return errors.Join(errs...)
+}
+
+// setString sets v from its text form in the environment and flags: scalars are parsed, other values are JSON.
+func setString(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("want an integer, got %q", s)
+		}
+		v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("want a number, got %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		// replaces rather than merges with the maps of the previous sources
+		v.Set(reflect.Zero(v.Type()))
+		return json.Unmarshal([]byte(s), v.Addr().Interface())
+	}
+	return nil
+}
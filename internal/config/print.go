@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+)
+
+const mask = "*****"
+
+// secretParam matches the query parameters of URLs likely to hold credentials.
+var secretParam = regexp.MustCompile(`(?i)token|key|secret|pass|sig|auth`)
+
+// Print writes the configuration to w as YAML or JSON, by the keys of the config file, with its secrets masked.
+func (c *Config) Print(w io.Writer, format string) error {
+	values := map[string]interface{}{}
+	v := reflect.ValueOf(c).Elem()
+	for _, f := range fields {
+		values[f.key] = masked(f, v.FieldByIndex(f.Index).Interface())
+	}
+	b, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "json":
+		b = append(b, '\n')
+	case "yaml":
+		if b, err = yaml.JSONToYAML(b); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q, want yaml or json", format)
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// masked returns value with the secrets of f hidden: URLs lose their password and the values of the query parameters
+// matching secretParam, other secrets are replaced as a whole.
+func masked(f field, value interface{}) interface{} {
+	switch f.secret {
+	case "":
+		return value
+	case "url":
+		switch value := value.(type) {
+		case string:
+			if value == "" {
+				return value
+			}
+			return maskURL(value)
+		case []string:
+			if value == nil {
+				return value
+			}
+			list := make([]string, len(value))
+			for i, u := range value {
+				list[i] = maskURL(u)
+			}
+			return list
+		case map[string]string:
+			if value == nil {
+				return value
+			}
+			m := make(map[string]string, len(value))
+			for k, u := range value {
+				m[k] = maskURL(u)
+			}
+			return m
+		}
+	}
+	if reflect.ValueOf(value).IsZero() {
+		return value
+	}
+	return mask
+}
+
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return mask
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for k := range query {
+			if secretParam.MatchString(k) {
+				query.Set(k, mask)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,126 @@
+// Package fiberutil holds helpers shared by the fiber middlewares.
+package fiberutil
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StatusCode returns the status code the response to c will have once err, returned by the next handlers, has
+// been handled by the default error handler.
+func StatusCode(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var e *fiber.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// NotModified sets the ETag and Last-Modified headers of the response to c, skipping an empty etag or zero
+// lastModified, and reports whether the client already has this representation, in which case the handler answers
+// 304. If-None-Match is checked first; If-Modified-Since only when the request has no If-None-Match.
+func NotModified(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		c.Set(fiber.HeaderETag, etag)
+	}
+	if !lastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		return etag != "" && MatchesETag(ifNoneMatch, etag)
+	}
+	if ifModifiedSince := c.Get(fiber.HeaderIfModifiedSince); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		// Last-Modified has a precision of a second
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// MatchesETag reports whether the If-None-Match header value ifNoneMatch lists etag, comparing weakly.
+func MatchesETag(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// targetsSegment is the segment of the routes of a monitoring target preceding its name, e.g. /targets/:name/tables.
+const targetsSegment = "targets"
+
+// pathKey is the key of the normalized path of a request in its locals.
+type pathKey struct{}
+
+// Path returns the path of c normalized by NormalizePath, computing it once per request.
+func Path(c *fiber.Ctx) string {
+	if path, ok := c.Locals(pathKey{}).(string); ok {
+		return path
+	}
+	path := NormalizePath(c.Path())
+	c.Locals(pathKey{}, path)
+	return path
+}
+
+// NormalizePath lowercases path and removes its trailing slashes, as the routes are neither case sensitive nor
+// strict about trailing slashes. The names of the monitoring targets, which are case sensitive, keep their case.
+func NormalizePath(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+	segments := strings.Split(path, "/")
+	for i := 0; i < len(segments); i++ {
+		segments[i] = strings.ToLower(segments[i])
+		if segments[i] == targetsSegment {
+			// skips the target name
+			i++
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// LookupRoute returns the value of path in routes, which maps path templates of routes, e.g. /entry/:id, to values.
+// When several templates match, the one with the most literal segments wins. It returns false when none matches.
+// Literal segments are compared regardless of case, path should be normalized by NormalizePath.
+func LookupRoute[V any](routes map[string]V, path string) (V, bool) {
+	var value V
+	best := -1
+	for template, v := range routes {
+		if literals, ok := match(template, path); ok && literals > best {
+			value, best = v, literals
+		}
+	}
+	return value, best >= 0
+}
+
+// match reports whether path matches template, whose segments starting with ':' match any segment, and returns the
+// number of literal segments of template.
+func match(template, path string) (literals int, ok bool) {
+	want := strings.Split(strings.Trim(template, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return 0, false
+	}
+	for i, segment := range want {
+		if strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if !strings.EqualFold(segment, got[i]) {
+			return 0, false
+		}
+		literals++
+	}
+	return literals, true
+}
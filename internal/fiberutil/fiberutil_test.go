@@ -0,0 +1,99 @@
+package fiberutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLookupRoute(t *testing.T) {
+	routes := map[string]time.Duration{
+		"/entry/:id":               time.Second,
+		"/entry/new":               2 * time.Second,
+		"/targets/:name/:resource": 3 * time.Second,
+		"/slow-queries":            4 * time.Second,
+	}
+	tests := []struct {
+		path string
+		want time.Duration
+		ok   bool
+	}{
+		{"/entry/42", time.Second, true},
+		{"/entry/new", 2 * time.Second, true},
+		{"/targets/orders/tables", 3 * time.Second, true},
+		{"/slow-queries/", 4 * time.Second, true},
+		{"/Slow-Queries", 4 * time.Second, true},
+		{"/slow-queries/history", 0, false},
+		{"/entries", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := LookupRoute(routes, tt.path); got != tt.want || ok != tt.ok {
+			t.Errorf("LookupRoute(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "/"},
+		{"/Entries/", "/entries"},
+		{"/ENTRY/42//", "/entry/42"},
+		{"/Targets/Prod/Slow-Queries/", "/targets/Prod/slow-queries"},
+		{"/targets/prod/tables", "/targets/prod/tables"},
+		{"/targets/targets/Tables", "/targets/targets/tables"},
+		{"/Targets/", "/targets"},
+	}
+	for _, tt := range tests {
+		if got := NormalizePath(tt.path); got != tt.want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2023, 6, 1, 12, 0, 0, 500, time.UTC)
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		if NotModified(c, `"v1"`, modified) {
+			return c.SendStatus(http.StatusNotModified)
+		}
+		return c.SendString("entry")
+	})
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   int
+	}{
+		{"unconditional", nil, http.StatusOK},
+		{"etag match", map[string]string{fiber.HeaderIfNoneMatch: `"v0", W/"v1"`}, http.StatusNotModified},
+		{"etag mismatch", map[string]string{fiber.HeaderIfNoneMatch: `"v0"`}, http.StatusOK},
+		{"not modified since", map[string]string{fiber.HeaderIfModifiedSince: "Thu, 01 Jun 2023 12:00:00 GMT"}, http.StatusNotModified},
+		{"modified since", map[string]string{fiber.HeaderIfModifiedSince: "Thu, 01 Jun 2023 11:59:59 GMT"}, http.StatusOK},
+		{"etag checked first", map[string]string{
+			fiber.HeaderIfNoneMatch:     `"v0"`,
+			fiber.HeaderIfModifiedSince: "Thu, 01 Jun 2023 12:00:00 GMT",
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+		if resp.Header.Get(fiber.HeaderETag) != `"v1"` || resp.Header.Get(fiber.HeaderLastModified) != "Thu, 01 Jun 2023 12:00:00 GMT" {
+			t.Errorf("%s: validators %v, want ETag and Last-Modified", tt.name, resp.Header)
+		}
+	}
+}
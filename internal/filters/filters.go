@@ -0,0 +1,83 @@
+// Package filters holds the resources accepting filter expressions: the model each one is filtered on and the
+// hooks mapping its filter fields to columns. It validates filters without a database connection, so that the
+// configuration and the alert rules can be checked independently of the repositories.
+package filters
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-pg/pg/v10/orm"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/pkg/listing"
+)
+
+// Resource is a resource accepting filter expressions.
+type Resource struct {
+	// Model is a pointer to the model whose fields the filter compares.
+	Model interface{}
+	// Config maps the filter fields that are not columns of Model.
+	Config listing.FilterConfig
+}
+
+var (
+	Entries = Resource{&model.Entry{}, listing.FilterConfig{}}
+	// SlowQueries compares database_name with the datname column of pg_stat_activity.
+	SlowQueries = Resource{&model.SlowQueryRecord{}, listing.FilterConfig{
+		Hooks: map[string]listing.FilterHook{
+			"database_name": listing.RenameFieldAndMapValuesFilterHook("datname", func(value interface{}) (interface{}, error) {
+				return value.(string), nil
+			}),
+		},
+	}}
+	BlockingQueries      = Resource{&model.BlockingQueryRecord{}, listing.FilterConfig{}}
+	SlowQueryHistory     = Resource{&model.SlowQueryHistoryRecord{}, listing.FilterConfig{}}
+	Replication          = Resource{&model.ReplicationRecord{}, listing.FilterConfig{}}
+	ReplicationReceivers = Resource{&model.WALReceiverRecord{}, listing.FilterConfig{}}
+	ReplicationSlots     = Resource{&model.ReplicationSlotRecord{}, listing.FilterConfig{}}
+	Tables               = Resource{&model.TableHealthRecord{}, listing.FilterConfig{}}
+	Indexes              = Resource{&model.IndexHealthRecord{}, listing.FilterConfig{}}
+)
+
+// resources maps the names of the resources, as given to Validate, to them.
+var resources = map[string]Resource{
+	"entries":               Entries,
+	"slow-queries":          SlowQueries,
+	"blocking":              BlockingQueries,
+	"history":               SlowQueryHistory,
+	"replication":           Replication,
+	"replication-receivers": ReplicationReceivers,
+	"replication-slots":     ReplicationSlots,
+	"tables":                Tables,
+	"indexes":               Indexes,
+}
+
+// Resources lists the resources accepted by Validate.
+func Resources() []string {
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the filter expression against the model of resource, e.g. "entries", without querying the
+// database, and returns the SELECT statement it translates to. The statement of a system view lacks its FROM
+// clause.
+func Validate(resource, filter string) (string, error) {
+	r, ok := resources[resource]
+	if !ok {
+		return "", fmt.Errorf("unknown resource %q", resource)
+	}
+	query := orm.NewQuery(nil, r.Model)
+	if err := listing.ApplyFilters(filter, r.Config, query); err != nil {
+		return "", err
+	}
+	b, err := orm.NewSelectQuery(query).AppendQuery(orm.NewFormatter(), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,37 @@
+package filters
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		resource string
+		filter   string
+		want     string
+		wantErr  bool
+	}{
+		{"entries", "", `FROM "entries" AS "entry" WHERE "entry"."delete_time" IS NULL`, false},
+		{"entries", `create_time > "2023-01-01"`, `("entry"."create_time" > '2023-01-01')`, false},
+		{"slow-queries", `database_name = "app" AND state:"act"`, `("pg_stat_activity"."datname" = 'app') AND ("pg_stat_activity"."state" LIKE '%act%')`, false},
+		{"tables", `dead_tuples > 1000`, `("table_health"."dead_tuples" > 1000)`, false},
+		{"entries", `datname = "app"`, "", true},
+		{"slow-queries", `duration_ms >`, "", true},
+		{"unknown", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Validate(tt.resource, tt.filter)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Validate(%q, %q) = %q, want an error", tt.resource, tt.filter, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Validate(%q, %q): %v", tt.resource, tt.filter, err)
+		} else if !strings.Contains(got, tt.want) {
+			t.Errorf("Validate(%q, %q) = %q, want it to contain %q", tt.resource, tt.filter, got, tt.want)
+		}
+	}
+}
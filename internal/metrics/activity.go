@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+)
+
+var (
+	backendsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pg", "backends"),
+		"Client backends of pg_stat_activity by state.", []string{"target", "state"}, nil)
+	waitingDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pg", "waiting_backends"),
+		"Client backends waiting for a lock.", []string{"target"}, nil)
+	longestQueryDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pg", "longest_query_seconds"),
+		"Running time of the longest active query.", []string{"target"}, nil)
+	upDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "pg", "up"),
+		"Whether pg_stat_activity of the target could be read.", []string{"target"}, nil)
+)
+
+// ActivityCollector reads pg_stat_activity of every target at scrape time.
+type ActivityCollector struct {
+	registry *targets.Registry
+	timeout  time.Duration
+	logger   *logrus.Entry
+}
+
+// NewActivityCollector returns a collector querying the targets of registry, each bounded by timeout.
+func NewActivityCollector(registry *targets.Registry, timeout time.Duration, logger *logrus.Entry) *ActivityCollector {
+	return &ActivityCollector{registry: registry, timeout: timeout, logger: logger.WithField("component", "metrics")}
+}
+
+func (a *ActivityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- backendsDesc
+	ch <- waitingDesc
+	ch <- longestQueryDesc
+	ch <- upDesc
+}
+
+func (a *ActivityCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	all := a.registry.All()
+	records, failed := targets.FanOut(ctx, all, func(ctx context.Context, t *targets.Target) ([]*targetStates, error) {
+		states, err := t.Monitor.BackendStates(ctx)
+		return []*targetStates{{target: t.Name, states: states}}, err
+	})
+	for name, err := range failed {
+		a.logger.WithField("target", name).Errorf("failed to read backend states: %v", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0, name)
+	}
+	for _, r := range records {
+		var waiting int64
+		var longest float64
+		for _, s := range r.states {
+			ch <- prometheus.MustNewConstMetric(backendsDesc, prometheus.GaugeValue, float64(s.Backends), r.target, s.State)
+			waiting += s.WaitingBackends
+			if s.LongestQuerySeconds > longest {
+				longest = s.LongestQuerySeconds
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(waitingDesc, prometheus.GaugeValue, float64(waiting), r.target)
+		ch <- prometheus.MustNewConstMetric(longestQueryDesc, prometheus.GaugeValue, longest, r.target)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1, r.target)
+	}
+}
+
+type targetStates struct {
+	target string
+	states []*model.BackendStateRecord
+}
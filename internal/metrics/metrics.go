@@ -0,0 +1,79 @@
+// Package metrics exposes the service and database activity to Prometheus.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/fiberutil"
+)
+
+const namespace = "city_falcon"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Response cache lookups by result: hit, miss or unreachable (not cacheable).",
+	}, []string{"result"})
+
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of database queries by database, SQL command and status (ok or error).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"database", "operation", "status"})
+)
+
+// CacheHeader is the response header in which the cache middleware reports hits and misses.
+const CacheHeader = "X-Cache"
+
+// Middleware counts requests and measures their latency, labelled with the route pattern rather than the path to
+// keep the cardinality bounded, and counts the cache results reported in CacheHeader.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		route := c.Route().Path
+		httpRequests.WithLabelValues(c.Method(), route, strconv.Itoa(fiberutil.StatusCode(c, err))).Inc()
+		httpDuration.WithLabelValues(c.Method(), route).Observe(time.Since(start).Seconds())
+		if result := c.GetRespHeader(CacheHeader); result != "" {
+			cacheRequests.WithLabelValues(result).Inc()
+		}
+		return err
+	}
+}
+
+// Handler serves the metrics of the default registry in the Prometheus exposition format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.Handler())
+}
+
+// ObserveQuery records a query on database taking d. A nil err is recorded as ok.
+func ObserveQuery(database, operation string, d time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	queryDuration.WithLabelValues(database, operation, status).Observe(d.Seconds())
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+)
+
+func TestMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/entry/:id", func(c *fiber.Ctx) error {
+		c.Set(CacheHeader, "hit")
+		return c.SendString("ok")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.NewError(http.StatusBadRequest, "bad")
+	})
+
+	hits := testutil.ToFloat64(cacheRequests.WithLabelValues("hit"))
+	for _, path := range []string{"/entry/1", "/entry/2", "/fail"} {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/entry/:id", "200")); got != 2 {
+		t.Errorf("requests of /entry/:id = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/fail", "400")); got != 1 {
+		t.Errorf("requests of /fail = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(cacheRequests.WithLabelValues("hit")) - hits; got != 2 {
+		t.Errorf("cache hits = %v, want 2", got)
+	}
+}
+
+func TestObserveQuery(t *testing.T) {
+	ObserveQuery("db:5432/test", "select", 0, nil)
+	ObserveQuery("db:5432/test", "select", 0, errors.New("boom"))
+	if got := testutil.CollectAndCount(queryDuration, namespace+"_db_query_duration_seconds"); got < 2 {
+		t.Errorf("got %d query series, want at least 2", got)
+	}
+}
+
+type fakeMonitor struct {
+	dataprovider.Monitor
+
+	states []*model.BackendStateRecord
+	err    error
+}
+
+func (f *fakeMonitor) BackendStates(ctx context.Context) ([]*model.BackendStateRecord, error) {
+	return f.states, f.err
+}
+
+func TestActivityCollector(t *testing.T) {
+	registry, err := targets.NewRegistry(
+		&targets.Target{Name: "a", Monitor: &fakeMonitor{states: []*model.BackendStateRecord{
+			{State: "active", Backends: 3, WaitingBackends: 1, LongestQuerySeconds: 12.5},
+			{State: "idle", Backends: 5},
+		}}},
+		&targets.Target{Name: "b", Monitor: &fakeMonitor{err: errors.New("connection refused")}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewActivityCollector(registry, time.Second, logrus.New().WithField("test", true))
+
+	want := `
+# HELP city_falcon_pg_backends Client backends of pg_stat_activity by state.
+# TYPE city_falcon_pg_backends gauge
+city_falcon_pg_backends{state="active",target="a"} 3
+city_falcon_pg_backends{state="idle",target="a"} 5
+# HELP city_falcon_pg_longest_query_seconds Running time of the longest active query.
+# TYPE city_falcon_pg_longest_query_seconds gauge
+city_falcon_pg_longest_query_seconds{target="a"} 12.5
+# HELP city_falcon_pg_up Whether pg_stat_activity of the target could be read.
+# TYPE city_falcon_pg_up gauge
+city_falcon_pg_up{target="a"} 1
+city_falcon_pg_up{target="b"} 0
+# HELP city_falcon_pg_waiting_backends Client backends waiting for a lock.
+# TYPE city_falcon_pg_waiting_backends gauge
+city_falcon_pg_waiting_backends{target="a"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,126 @@
+package model
+
+import "sort"
+
+type backendKey struct {
+	target string
+	pid    int
+}
+
+// BuildBlockingTrees folds blocker/blocked edges into trees rooted at the backends that hold locks without
+// waiting on anyone themselves. Backends that only appear inside a cycle (a deadlock not yet detected by
+// Postgres) are rooted at the lowest pid of the cycle so that every edge is represented. Records of different
+// targets never share a tree.
+func BuildBlockingTrees(records []*BlockingQueryRecord) []*BlockingNode {
+	nodes := map[backendKey]*BlockingNode{}
+	children := map[backendKey][]backendKey{}
+	blocked := map[backendKey]bool{}
+
+	node := func(key backendKey) *BlockingNode {
+		n, ok := nodes[key]
+		if !ok {
+			n = &BlockingNode{Target: key.target, PID: key.pid}
+			nodes[key] = n
+		}
+		return n
+	}
+
+	for _, r := range records {
+		blockerKey := backendKey{r.Target, r.BlockingPID}
+		blocker := node(blockerKey)
+		blocker.UserName = r.BlockingUserName
+		blocker.State = r.BlockingState
+		blocker.Query = r.BlockingQuery
+		blocker.DurationMS = r.BlockingDurationMS
+		if blocker.LockMode == "" {
+			blocker.LockType = r.LockType
+			blocker.LockMode = r.BlockingLockMode
+			blocker.Relation = r.RelationName
+		}
+
+		waiterKey := backendKey{r.Target, r.BlockedPID}
+		waiter := node(waiterKey)
+		waiter.UserName = r.BlockedUserName
+		waiter.State = r.BlockedState
+		waiter.Query = r.BlockedQuery
+		waiter.DurationMS = r.BlockedDurationMS
+		waiter.LockType = r.LockType
+		waiter.LockMode = r.LockMode
+		waiter.Relation = r.RelationName
+
+		children[blockerKey] = append(children[blockerKey], waiterKey)
+		blocked[waiterKey] = true
+	}
+
+	keys := make([]backendKey, 0, len(nodes))
+	for key := range nodes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].target != keys[j].target {
+			return keys[i].target < keys[j].target
+		}
+		return keys[i].pid < keys[j].pid
+	})
+
+	visited := map[backendKey]bool{}
+	var attach func(key backendKey) *BlockingNode
+	attach = func(key backendKey) *BlockingNode {
+		visited[key] = true
+		n := nodes[key]
+		for _, child := range children[key] {
+			if visited[child] {
+				continue
+			}
+			n.Blocked = append(n.Blocked, attach(child))
+		}
+		return n
+	}
+
+	var roots []*BlockingNode
+	for _, key := range keys {
+		if !blocked[key] && !visited[key] {
+			roots = append(roots, attach(key))
+		}
+	}
+	for _, key := range keys {
+		if !visited[key] {
+			roots = append(roots, attach(key))
+		}
+	}
+	return roots
+}
+
+// PageBlockingTrees builds the trees of records and returns the page of at most pageSize of them starting at the
+// pageOffset-th root, together with the edges of the trees of the page. Paging the trees rather than the edges
+// keeps every chain whole.
+func PageBlockingTrees(records []*BlockingQueryRecord, pageOffset, pageSize int) BlockingQueriesResponse {
+	roots := BuildBlockingTrees(records)
+	if pageOffset < 0 {
+		pageOffset = 0
+	}
+	if pageOffset > len(roots) {
+		pageOffset = len(roots)
+	}
+	if pageSize < 0 || pageOffset+pageSize > len(roots) {
+		pageSize = len(roots) - pageOffset
+	}
+	roots = roots[pageOffset : pageOffset+pageSize]
+
+	inPage := map[backendKey]bool{}
+	var mark func(nodes []*BlockingNode)
+	mark = func(nodes []*BlockingNode) {
+		for _, n := range nodes {
+			inPage[backendKey{n.Target, n.PID}] = true
+			mark(n.Blocked)
+		}
+	}
+	mark(roots)
+	var edges []*BlockingQueryRecord
+	for _, r := range records {
+		if inPage[backendKey{r.Target, r.BlockingPID}] {
+			edges = append(edges, r)
+		}
+	}
+	return BlockingQueriesResponse{Blocking: edges, Trees: roots}
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+)
+
+func flatten(nodes []*BlockingNode, depth int, out map[int]int) {
+	for _, n := range nodes {
+		out[n.PID] = depth
+		flatten(n.Blocked, depth+1, out)
+	}
+}
+
+func TestBuildBlockingTrees(t *testing.T) {
+	tests := []struct {
+		name      string
+		records   []*BlockingQueryRecord
+		wantRoots []int
+		wantDepth map[int]int
+	}{
+		{"empty", nil, nil, map[int]int{}},
+		{
+			"chain",
+			[]*BlockingQueryRecord{
+				{BlockedPID: 3, BlockingPID: 2},
+				{BlockedPID: 2, BlockingPID: 1},
+			},
+			[]int{1},
+			map[int]int{1: 0, 2: 1, 3: 2},
+		},
+		{
+			"two blockers share a waiter",
+			[]*BlockingQueryRecord{
+				{BlockedPID: 10, BlockingPID: 1},
+				{BlockedPID: 10, BlockingPID: 2},
+			},
+			[]int{1, 2},
+			map[int]int{1: 0, 2: 0, 10: 1},
+		},
+		{
+			"targets do not share pids",
+			[]*BlockingQueryRecord{
+				{Target: "a", BlockedPID: 2, BlockingPID: 1},
+				{Target: "b", BlockedPID: 3, BlockingPID: 2},
+			},
+			[]int{1, 2},
+			map[int]int{1: 0, 2: 0, 3: 1},
+		},
+		{
+			"cycle",
+			[]*BlockingQueryRecord{
+				{BlockedPID: 5, BlockingPID: 7},
+				{BlockedPID: 7, BlockingPID: 5},
+			},
+			[]int{5},
+			map[int]int{5: 0, 7: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots := BuildBlockingTrees(tt.records)
+			if len(roots) != len(tt.wantRoots) {
+				t.Fatalf("got %d roots, want %d", len(roots), len(tt.wantRoots))
+			}
+			for i, r := range roots {
+				if r.PID != tt.wantRoots[i] {
+					t.Errorf("root #%d = %d, want %d", i, r.PID, tt.wantRoots[i])
+				}
+			}
+			got := map[int]int{}
+			flatten(roots, 0, got)
+			for pid, depth := range tt.wantDepth {
+				if got[pid] != depth {
+					t.Errorf("depth of pid %d = %d, want %d", pid, got[pid], depth)
+				}
+			}
+			if len(got) != len(tt.wantDepth) {
+				t.Errorf("got %d nodes, want %d", len(got), len(tt.wantDepth))
+			}
+		})
+	}
+}
+
+func TestPageBlockingTrees(t *testing.T) {
+	// the chain 1 <- 2 <- 3 crosses the boundary of pages of two edges
+	records := []*BlockingQueryRecord{
+		{BlockedPID: 2, BlockingPID: 1},
+		{BlockedPID: 5, BlockingPID: 4},
+		{BlockedPID: 3, BlockingPID: 2},
+	}
+	tests := []struct {
+		offset, size int
+		wantRoots    []int
+		wantEdges    int
+	}{
+		{0, 1, []int{1}, 2},
+		{1, 1, []int{4}, 1},
+		{0, 10, []int{1, 4}, 3},
+		{2, 1, nil, 0},
+	}
+	for _, tt := range tests {
+		page := PageBlockingTrees(records, tt.offset, tt.size)
+		var roots []int
+		for _, r := range page.Trees {
+			roots = append(roots, r.PID)
+		}
+		if fmt.Sprint(roots) != fmt.Sprint(tt.wantRoots) {
+			t.Errorf("page %d+%d: roots %v, want %v", tt.offset, tt.size, roots, tt.wantRoots)
+		}
+		if len(page.Blocking) != tt.wantEdges {
+			t.Errorf("page %d+%d: %d edges, want %d", tt.offset, tt.size, len(page.Blocking), tt.wantEdges)
+		}
+	}
+	depths := map[int]int{}
+	flatten(PageBlockingTrees(records, 0, 1).Trees, 0, depths)
+	if depths[3] != 2 {
+		t.Errorf("chain of the first page cut: depths %v", depths)
+	}
+}
@@ -2,6 +2,9 @@ package model
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,37 +33,326 @@ func (e *Entry) BeforeUpdate(ctx context.Context) (context.Context, error) {
 	return ctx, nil
 }
 
-type ListEntriesRequest struct {
+// ETag is the strong entity tag of the entry, derived from its Version. Since updates need not bump Version, it
+// also covers the update and delete times.
+func (e *Entry) ETag() string {
+	tag := fmt.Sprintf("%d-%x", e.Version, e.UpdateTime.UnixNano())
+	if e.DeleteTime != nil {
+		tag += fmt.Sprintf("-%x", e.DeleteTime.UnixNano())
+	}
+	return `"` + tag + `"`
+}
+
+// LastModified is the time of the last change of the entry, its update or deletion.
+func (e *Entry) LastModified() time.Time {
+	if e.DeleteTime != nil && e.DeleteTime.After(e.UpdateTime) {
+		return *e.DeleteTime
+	}
+	return e.UpdateTime
+}
+
+// ListParams are the paging, ordering and filter parameters shared by the list requests.
+type ListParams struct {
 	PageSize   int    `json:"page_size,omitempty"`
 	PageOffset int    `json:"page_offset,omitempty"`
 	OrderBy    string `json:"order_by,omitempty"`
 	Filter     string
 }
 
+type ListEntriesRequest struct {
+	ListParams
+}
+
 type ListEntriesResponse struct {
 	Entries []*Entry `json:"entries,omitempty"`
 }
 
+// ETag is the weak entity tag of the page, derived from the IDs and entity tags of its entries.
+func (r ListEntriesResponse) ETag() string {
+	h := sha256.New()
+	for _, e := range r.Entries {
+		fmt.Fprintf(h, "%s %s\n", e.ID, e.ETag())
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
 type SlowQueryRecord struct {
-	tableName struct{} `pg:"pg_stat_activity,discard_unknown_columns"`
+	tableName struct{} `pg:"_,alias:pg_stat_activity,discard_unknown_columns"`
 
-	DatabaseName  string `pg:"datname" json:"database_name"`
-	PID           string `pg:"pid" json:"pid"`
-	UserName      string `pg:"usename" json:"user_name"`
-	ClientAddress string `pg:"client_addr" json:"client_address"`
-	BackendStart  string `pg:"backend_start" json:"backend_start"`
-	QueryStart    string `pg:"query_start" json:"query_start"`
-	State         string `pg:"state" json:"state"`
-	Query         string `pg:"query"  json:"query"`
+	DatabaseName    string `pg:"datname" json:"database_name"`
+	PID             string `pg:"pid" json:"pid"`
+	UserName        string `pg:"usename" json:"user_name"`
+	ClientAddress   string `pg:"client_addr" json:"client_address"`
+	BackendStart    string `pg:"backend_start" json:"backend_start"`
+	QueryStart      string `pg:"query_start" json:"query_start"`
+	State           string `pg:"state" json:"state"`
+	Query           string `pg:"query"  json:"query"`
+	DurationMS      int64  `pg:"duration_ms,use_zero" json:"duration_ms"`
+	Fingerprint     string `pg:"-" json:"fingerprint"`
+	NormalizedQuery string `pg:"-" json:"normalized_query"`
+	Target          string `pg:"-" json:"target,omitempty"`
 }
 
 type SlowQueriesRequest struct {
-	PageSize   int    `json:"page_size,omitempty"`
-	PageOffset int    `json:"page_offset,omitempty"`
-	OrderBy    string `json:"order_by,omitempty"`
-	Filter     string
+	ListParams
+	// ExcludeSelf leaves out the backend running the query, whose own activity could match the filter.
+	ExcludeSelf bool
 }
 
 type SlowQueriesResponse struct {
 	SlowQueries []*SlowQueryRecord `json:"slow_queries,omitempty"`
 }
+
+// SlowQueryGroup aggregates the slow queries sharing a fingerprint.
+type SlowQueryGroup struct {
+	Target          string   `json:"target,omitempty"`
+	Fingerprint     string   `json:"fingerprint"`
+	NormalizedQuery string   `json:"normalized_query"`
+	Count           int      `json:"count"`
+	MaxDurationMS   int64    `json:"max_duration_ms"`
+	PIDs            []string `json:"pids"`
+}
+
+// BlockingQueryRecord is a single blocker/blocked edge derived from pg_locks and pg_stat_activity.
+type BlockingQueryRecord struct {
+	tableName struct{} `pg:"_,alias:blocking_query,discard_unknown_columns"`
+
+	DatabaseName       string `pg:"database_name" json:"database_name"`
+	BlockedPID         int    `pg:"blocked_pid" json:"blocked_pid"`
+	BlockedUserName    string `pg:"blocked_user_name" json:"blocked_user_name"`
+	BlockedState       string `pg:"blocked_state" json:"blocked_state"`
+	BlockedQuery       string `pg:"blocked_query" json:"blocked_query"`
+	BlockedDurationMS  int64  `pg:"blocked_duration_ms,use_zero" json:"blocked_duration_ms"`
+	LockType           string `pg:"lock_type" json:"lock_type"`
+	LockMode           string `pg:"lock_mode" json:"lock_mode"`
+	RelationName       string `pg:"relation_name" json:"relation_name"`
+	BlockingPID        int    `pg:"blocking_pid" json:"blocking_pid"`
+	BlockingUserName   string `pg:"blocking_user_name" json:"blocking_user_name"`
+	BlockingState      string `pg:"blocking_state" json:"blocking_state"`
+	BlockingQuery      string `pg:"blocking_query" json:"blocking_query"`
+	BlockingLockMode   string `pg:"blocking_lock_mode" json:"blocking_lock_mode"`
+	BlockingDurationMS int64  `pg:"blocking_duration_ms,use_zero" json:"blocking_duration_ms"`
+	Target             string `pg:"-" json:"target,omitempty"`
+}
+
+type BlockingQueriesRequest struct {
+	ListParams
+}
+
+type BlockingQueriesResponse struct {
+	Blocking []*BlockingQueryRecord `json:"blocking,omitempty"`
+	Trees    []*BlockingNode        `json:"trees,omitempty"`
+}
+
+// BlockingNode is a backend in a blocking chain together with the backends waiting on it.
+type BlockingNode struct {
+	Target     string          `json:"target,omitempty"`
+	PID        int             `json:"pid"`
+	UserName   string          `json:"user_name"`
+	State      string          `json:"state"`
+	Query      string          `json:"query"`
+	DurationMS int64           `json:"duration_ms"`
+	LockType   string          `json:"lock_type,omitempty"`
+	LockMode   string          `json:"lock_mode,omitempty"`
+	Relation   string          `json:"relation_name,omitempty"`
+	Blocked    []*BlockingNode `json:"blocked,omitempty"`
+}
+
+// SlowQueryHistoryRecord is a query that was seen running longer than the sampling threshold. A record is
+// identified by the backend pid and the query start time, so repeated samples of the same execution update it.
+type SlowQueryHistoryRecord struct {
+	tableName struct{} `pg:"slow_query_history,discard_unknown_columns"`
+
+	PID           int       `pg:"pid,pk,type:integer" json:"pid"`
+	QueryStart    time.Time `pg:"query_start,pk" json:"query_start"`
+	DatabaseName  string    `pg:"database_name" json:"database_name"`
+	UserName      string    `pg:"user_name" json:"user_name"`
+	ClientAddress string    `pg:"client_address" json:"client_address"`
+	State         string    `pg:"state" json:"state"`
+	Query         string    `pg:"query" json:"query"`
+	FirstSeen     time.Time `pg:"first_seen,notnull" json:"first_seen"`
+	LastSeen      time.Time `pg:"last_seen,notnull" json:"last_seen"`
+	MaxDurationMS int64     `pg:"max_duration_ms,notnull,use_zero" json:"max_duration_ms"`
+	Samples       int       `pg:"samples,notnull,default:1" json:"samples"`
+}
+
+type SlowQueryHistoryRequest struct {
+	ListParams
+	// From and To restrict the result to records that were running at some point in [From, To]; zero values are unbounded.
+	From time.Time
+	To   time.Time
+}
+
+// PoolStats describes the connection pool of a database handle.
+type PoolStats struct {
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"`
+	TotalConns uint32 `json:"total_conns"`
+	IdleConns  uint32 `json:"idle_conns"`
+	StaleConns uint32 `json:"stale_conns"`
+}
+
+// TargetHealth is the result of checking a monitoring target.
+type TargetHealth struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	Pool      PoolStats `json:"pool"`
+}
+
+// ReadinessResponse tells whether the service can serve requests: its database answers and its schema migrations
+// are applied.
+type ReadinessResponse struct {
+	Ready       bool          `json:"ready"`
+	Database    *TargetHealth `json:"database"`
+	SchemaReady bool          `json:"schema_ready"`
+	SchemaError string        `json:"schema_error,omitempty"`
+}
+
+// StatusResponse describes the running service.
+type StatusResponse struct {
+	Version       string    `json:"version"`
+	GoVersion     string    `json:"go_version"`
+	StartTime     time.Time `json:"start_time"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Goroutines    int       `json:"goroutines"`
+	Pool          PoolStats `json:"pool"`
+}
+
+// ErrorResponse is the body of the requests that failed, e.g. with 504 when they timed out.
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type ReplicationRequest struct {
+	ListParams
+}
+
+// ReplicationRecord is a WAL sender from pg_stat_replication, i.e. a standby streaming from the server, with the
+// replication slot it uses if any.
+type ReplicationRecord struct {
+	tableName struct{} `pg:"_,alias:replication,discard_unknown_columns"`
+
+	PID             int    `pg:"pid" json:"pid"`
+	UserName        string `pg:"user_name" json:"user_name"`
+	ApplicationName string `pg:"application_name" json:"application_name"`
+	ClientAddress   string `pg:"client_address" json:"client_address"`
+	BackendStart    string `pg:"backend_start" json:"backend_start"`
+	State           string `pg:"state" json:"state"`
+	SyncState       string `pg:"sync_state" json:"sync_state"`
+	SentLSN         string `pg:"sent_lsn" json:"sent_lsn"`
+	WriteLSN        string `pg:"write_lsn" json:"write_lsn"`
+	FlushLSN        string `pg:"flush_lsn" json:"flush_lsn"`
+	ReplayLSN       string `pg:"replay_lsn" json:"replay_lsn"`
+	WriteLagMS      int64  `pg:"write_lag_ms,use_zero" json:"write_lag_ms"`
+	FlushLagMS      int64  `pg:"flush_lag_ms,use_zero" json:"flush_lag_ms"`
+	ReplayLagMS     int64  `pg:"replay_lag_ms,use_zero" json:"replay_lag_ms"`
+	ReplayLagBytes  int64  `pg:"replay_lag_bytes,use_zero" json:"replay_lag_bytes"`
+	SlotName        string `pg:"slot_name" json:"slot_name"`
+	Target          string `pg:"-" json:"target,omitempty"`
+}
+
+// WALReceiverRecord is the WAL receiver of a standby from pg_stat_wal_receiver.
+type WALReceiverRecord struct {
+	tableName struct{} `pg:"_,alias:wal_receiver,discard_unknown_columns"`
+
+	PID                int    `pg:"pid" json:"pid"`
+	Status             string `pg:"status" json:"status"`
+	SenderHost         string `pg:"sender_host" json:"sender_host"`
+	SenderPort         int    `pg:"sender_port" json:"sender_port"`
+	SlotName           string `pg:"slot_name" json:"slot_name"`
+	ReceiveStartLSN    string `pg:"receive_start_lsn" json:"receive_start_lsn"`
+	WrittenLSN         string `pg:"written_lsn" json:"written_lsn"`
+	FlushedLSN         string `pg:"flushed_lsn" json:"flushed_lsn"`
+	ReplayLSN          string `pg:"replay_lsn" json:"replay_lsn"`
+	LastMsgReceiptTime string `pg:"last_msg_receipt_time" json:"last_msg_receipt_time"`
+	LatestEndTime      string `pg:"latest_end_time" json:"latest_end_time"`
+	ReplayDelayMS      int64  `pg:"replay_delay_ms,use_zero" json:"replay_delay_ms"`
+	ReplayLagBytes     int64  `pg:"replay_lag_bytes,use_zero" json:"replay_lag_bytes"`
+	Target             string `pg:"-" json:"target,omitempty"`
+}
+
+// ReplicationSlotRecord is a replication slot from pg_replication_slots with the amount of WAL it retains.
+type ReplicationSlotRecord struct {
+	tableName struct{} `pg:"_,alias:replication_slot,discard_unknown_columns"`
+
+	SlotName          string `pg:"slot_name" json:"slot_name"`
+	Plugin            string `pg:"plugin" json:"plugin"`
+	SlotType          string `pg:"slot_type" json:"slot_type"`
+	Database          string `pg:"database" json:"database"`
+	Temporary         bool   `pg:"temporary,use_zero" json:"temporary"`
+	Active            bool   `pg:"active,use_zero" json:"active"`
+	ActivePID         int    `pg:"active_pid" json:"active_pid"`
+	RestartLSN        string `pg:"restart_lsn" json:"restart_lsn"`
+	ConfirmedFlushLSN string `pg:"confirmed_flush_lsn" json:"confirmed_flush_lsn"`
+	WALStatus         string `pg:"wal_status" json:"wal_status"`
+	RetainedBytes     int64  `pg:"retained_bytes,use_zero" json:"retained_bytes"`
+	Target            string `pg:"-" json:"target,omitempty"`
+}
+
+type TableHealthRequest struct {
+	ListParams
+}
+
+// TableHealthRecord combines pg_stat_user_tables with the on-disk size of a table.
+type TableHealthRecord struct {
+	tableName struct{} `pg:"_,alias:table_health,discard_unknown_columns"`
+
+	SchemaName       string  `pg:"schema_name" json:"schema_name"`
+	TableName        string  `pg:"table_name" json:"table_name"`
+	LiveTuples       int64   `pg:"live_tuples,use_zero" json:"live_tuples"`
+	DeadTuples       int64   `pg:"dead_tuples,use_zero" json:"dead_tuples"`
+	DeadTupleRatio   float64 `pg:"dead_tuple_ratio,use_zero" json:"dead_tuple_ratio"`
+	ModsSinceAnalyze int64   `pg:"mods_since_analyze,use_zero" json:"mods_since_analyze"`
+	SeqScan          int64   `pg:"seq_scan,use_zero" json:"seq_scan"`
+	SeqTupRead       int64   `pg:"seq_tup_read,use_zero" json:"seq_tup_read"`
+	IdxScan          int64   `pg:"idx_scan,use_zero" json:"idx_scan"`
+	IdxTupFetch      int64   `pg:"idx_tup_fetch,use_zero" json:"idx_tup_fetch"`
+	IndexScanRatio   float64 `pg:"index_scan_ratio,use_zero" json:"index_scan_ratio"`
+	LastVacuum       string  `pg:"last_vacuum" json:"last_vacuum"`
+	LastAutovacuum   string  `pg:"last_autovacuum" json:"last_autovacuum"`
+	LastAnalyze      string  `pg:"last_analyze" json:"last_analyze"`
+	LastAutoanalyze  string  `pg:"last_autoanalyze" json:"last_autoanalyze"`
+	VacuumCount      int64   `pg:"vacuum_count,use_zero" json:"vacuum_count"`
+	AutovacuumCount  int64   `pg:"autovacuum_count,use_zero" json:"autovacuum_count"`
+	AnalyzeCount     int64   `pg:"analyze_count,use_zero" json:"analyze_count"`
+	AutoanalyzeCount int64   `pg:"autoanalyze_count,use_zero" json:"autoanalyze_count"`
+	TotalBytes       int64   `pg:"total_bytes,use_zero" json:"total_bytes"`
+	TableBytes       int64   `pg:"table_bytes,use_zero" json:"table_bytes"`
+	IndexBytes       int64   `pg:"index_bytes,use_zero" json:"index_bytes"`
+	Target           string  `pg:"-" json:"target,omitempty"`
+}
+
+// IndexHealthRecord combines pg_stat_user_indexes with the size and definition of an index. Unused is set for
+// indexes that were never scanned and do not enforce a constraint, DuplicateOf lists indexes with the same
+// definition and CoveredBy the indexes whose leading columns make this one redundant.
+type IndexHealthRecord struct {
+	tableName struct{} `pg:"_,alias:index_health,discard_unknown_columns"`
+
+	SchemaName  string `pg:"schema_name" json:"schema_name"`
+	TableName   string `pg:"table_name" json:"table_name"`
+	IndexName   string `pg:"index_name" json:"index_name"`
+	Definition  string `pg:"definition" json:"definition"`
+	IdxScan     int64  `pg:"idx_scan,use_zero" json:"idx_scan"`
+	IdxTupRead  int64  `pg:"idx_tup_read,use_zero" json:"idx_tup_read"`
+	IdxTupFetch int64  `pg:"idx_tup_fetch,use_zero" json:"idx_tup_fetch"`
+	IndexBytes  int64  `pg:"index_bytes,use_zero" json:"index_bytes"`
+	IsUnique    bool   `pg:"is_unique,use_zero" json:"is_unique"`
+	IsPrimary   bool   `pg:"is_primary,use_zero" json:"is_primary"`
+	Unused      bool   `pg:"unused,use_zero" json:"unused"`
+	Duplicate   bool   `pg:"duplicate,use_zero" json:"duplicate"`
+	DuplicateOf string `pg:"duplicate_of" json:"duplicate_of,omitempty"`
+	CoveredBy   string `pg:"covered_by" json:"covered_by,omitempty"`
+	Target      string `pg:"-" json:"target,omitempty"`
+}
+
+// BackendStateRecord summarizes the client backends of pg_stat_activity in one state.
+type BackendStateRecord struct {
+	State               string  `pg:"state" json:"state"`
+	Backends            int64   `pg:"backends,use_zero" json:"backends"`
+	WaitingBackends     int64   `pg:"waiting_backends,use_zero" json:"waiting_backends"`
+	LongestQuerySeconds float64 `pg:"longest_query_seconds,use_zero" json:"longest_query_seconds"`
+}
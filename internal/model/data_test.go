@@ -0,0 +1,58 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEntry_ETag(t *testing.T) {
+	updated := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	e := &Entry{ID: uuid.New(), Version: 1, UpdateTime: updated}
+	tag := e.ETag()
+	if !strings.HasPrefix(tag, `"1-`) || !strings.HasSuffix(tag, `"`) {
+		t.Errorf("ETag() = %s, want a strong tag starting with the version", tag)
+	}
+	changes := map[string]func(e *Entry){
+		"version": func(e *Entry) { e.Version = 2 },
+		"update":  func(e *Entry) { e.UpdateTime = updated.Add(time.Millisecond) },
+		"delete":  func(e *Entry) { deleted := updated.Add(time.Hour); e.DeleteTime = &deleted },
+	}
+	for name, change := range changes {
+		changed := *e
+		change(&changed)
+		if changed.ETag() == tag {
+			t.Errorf("ETag() unchanged by the %s", name)
+		}
+	}
+}
+
+func TestEntry_LastModified(t *testing.T) {
+	updated := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	deleted := updated.Add(time.Hour)
+	if got := (&Entry{UpdateTime: updated}).LastModified(); !got.Equal(updated) {
+		t.Errorf("LastModified() = %v, want the update time", got)
+	}
+	if got := (&Entry{UpdateTime: updated, DeleteTime: &deleted}).LastModified(); !got.Equal(deleted) {
+		t.Errorf("LastModified() of a deleted entry = %v, want the delete time", got)
+	}
+}
+
+func TestListEntriesResponse_ETag(t *testing.T) {
+	a := &Entry{ID: uuid.New(), Version: 1}
+	b := &Entry{ID: uuid.New(), Version: 1}
+	tag := (ListEntriesResponse{Entries: []*Entry{a, b}}).ETag()
+	if !strings.HasPrefix(tag, `W/"`) {
+		t.Errorf("ETag() = %s, want a weak tag", tag)
+	}
+	if (ListEntriesResponse{Entries: []*Entry{a, b}}).ETag() != tag {
+		t.Error("ETag() differs for the same page")
+	}
+	for _, page := range [][]*Entry{{b, a}, {a}, {a, {ID: b.ID, Version: 2}}} {
+		if (ListEntriesResponse{Entries: page}).ETag() == tag {
+			t.Errorf("ETag() of a different page %v equals %s", page, tag)
+		}
+	}
+}
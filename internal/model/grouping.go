@@ -0,0 +1,30 @@
+package model
+
+import "sort"
+
+// GroupSlowQueries aggregates records by Fingerprint, ordered by the longest running query of each group and
+// then by fingerprint so that the order is stable between calls.
+func GroupSlowQueries(records []*SlowQueryRecord) []*SlowQueryGroup {
+	var groups []*SlowQueryGroup
+	byFingerprint := map[string]*SlowQueryGroup{}
+	for _, r := range records {
+		g, ok := byFingerprint[r.Fingerprint]
+		if !ok {
+			g = &SlowQueryGroup{Fingerprint: r.Fingerprint, NormalizedQuery: r.NormalizedQuery}
+			byFingerprint[r.Fingerprint] = g
+			groups = append(groups, g)
+		}
+		g.Count++
+		g.PIDs = append(g.PIDs, r.PID)
+		if r.DurationMS > g.MaxDurationMS {
+			g.MaxDurationMS = r.DurationMS
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].MaxDurationMS != groups[j].MaxDurationMS {
+			return groups[i].MaxDurationMS > groups[j].MaxDurationMS
+		}
+		return groups[i].Fingerprint < groups[j].Fingerprint
+	})
+	return groups
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupSlowQueries(t *testing.T) {
+	records := []*SlowQueryRecord{
+		{PID: "1", Fingerprint: "a", NormalizedQuery: "select ?", DurationMS: 10},
+		{PID: "2", Fingerprint: "b", NormalizedQuery: "select * from t", DurationMS: 500},
+		{PID: "3", Fingerprint: "a", NormalizedQuery: "select ?", DurationMS: 700},
+		{PID: "4", Fingerprint: "c", NormalizedQuery: "select ?, ?", DurationMS: 500},
+	}
+	want := []*SlowQueryGroup{
+		{Fingerprint: "a", NormalizedQuery: "select ?", Count: 2, MaxDurationMS: 700, PIDs: []string{"1", "3"}},
+		{Fingerprint: "b", NormalizedQuery: "select * from t", Count: 1, MaxDurationMS: 500, PIDs: []string{"2"}},
+		{Fingerprint: "c", NormalizedQuery: "select ?, ?", Count: 1, MaxDurationMS: 500, PIDs: []string{"4"}},
+	}
+	if got := GroupSlowQueries(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupSlowQueries() = %+v, want %+v", got, want)
+	}
+	if got := GroupSlowQueries(nil); got != nil {
+		t.Errorf("GroupSlowQueries(nil) = %+v, want nil", got)
+	}
+}
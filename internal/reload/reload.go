@@ -0,0 +1,116 @@
+// Package reload reloads the configuration of the server while it runs.
+package reload
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/config"
+)
+
+// pollInterval is how often the config file is checked for changes.
+const pollInterval = 5 * time.Second
+
+// Reloader reloads the configuration of the server on SIGHUP and when its config file changes. Only the settings
+// tagged reload in config.Config may change: a reload changing any other setting, or failing validation, is
+// rejected as a whole and the configuration in effect is kept.
+type Reloader struct {
+	load         func() (*config.Config, error)
+	apply        func(*config.Config) error
+	logger       *logrus.Entry
+	pollInterval time.Duration
+	current      atomic.Pointer[config.Config]
+}
+
+// New starts from cfg. load reads the configuration again from every source and apply makes the
+// reloadable settings of the new configuration take effect, failing if they cannot.
+func New(cfg *config.Config, load func() (*config.Config, error), apply func(*config.Config) error, logger *logrus.Entry) *Reloader {
+	r := &Reloader{load: load, apply: apply, logger: logger.WithField("component", "config"), pollInterval: pollInterval}
+	r.current.Store(cfg)
+	return r
+}
+
+// Config returns the configuration in effect.
+func (r *Reloader) Config() *config.Config {
+	return r.current.Load()
+}
+
+// Run reloads the configuration when needed until ctx is cancelled.
+func (r *Reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	sum := r.fileSum()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			sum = r.fileSum()
+			r.reload("SIGHUP")
+		case <-ticker.C:
+			if s := r.fileSum(); s != sum {
+				sum = s
+				r.reload("config file changed")
+			}
+		}
+	}
+}
+
+// fileSum returns the checksum of the config file, empty when there is none or it cannot be read. Comparing the
+// content rather than the modification time also catches files replaced by a rename, e.g. mounted ConfigMaps.
+func (r *Reloader) fileSum() string {
+	path := r.Config().File()
+	if path == "" {
+		return ""
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return string(sum[:])
+}
+
+func (r *Reloader) reload(reason string) {
+	logger := r.logger.WithField("reason", reason)
+	next, err := r.load()
+	if err != nil {
+		logger.Errorf("configuration reload rejected: %v", err)
+		return
+	}
+	changes := config.Diff(r.Config(), next)
+	if len(changes) == 0 {
+		logger.Info("configuration reloaded, nothing changed")
+		return
+	}
+	var fixed []string
+	for _, c := range changes {
+		if !c.Reloadable {
+			fixed = append(fixed, c.Env)
+		}
+	}
+	if len(fixed) > 0 {
+		logger.Errorf("configuration reload rejected: %s cannot change without a restart", strings.Join(fixed, ", "))
+		return
+	}
+	if err := r.apply(next); err != nil {
+		logger.Errorf("configuration reload rejected: %v", err)
+		return
+	}
+	r.current.Store(next)
+	for _, c := range changes {
+		logger.WithFields(logrus.Fields{"setting": c.Env, "old": c.Old, "new": c.New}).Info("configuration changed")
+	}
+}
@@ -0,0 +1,147 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/config"
+)
+
+// testReloader loads its configuration from a config file holding content, reporting the configurations applied.
+type testReloader struct {
+	*Reloader
+	path     string
+	applied  []*config.Config
+	applyErr error
+	logs     *test.Hook
+}
+
+func newTestReloader(t *testing.T, content string) *testReloader {
+	t.Helper()
+	tr := &testReloader{path: filepath.Join(t.TempDir(), "config.yaml")}
+	tr.write(t, content)
+	load := func() (*config.Config, error) {
+		cfg, _, err := config.Load([]string{"-config", tr.path}, func(string) string { return "" })
+		return cfg, err
+	}
+	cfg, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, hook := test.NewNullLogger()
+	tr.logs = hook
+	tr.Reloader = New(cfg, load, func(next *config.Config) error {
+		if tr.applyErr != nil {
+			return tr.applyErr
+		}
+		tr.applied = append(tr.applied, next)
+		return nil
+	}, logrus.NewEntry(logger))
+	return tr
+}
+
+func (tr *testReloader) write(t *testing.T, content string) {
+	t.Helper()
+	if err := os.WriteFile(tr.path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rejected returns the message of the last rejected reload, empty if none.
+func (tr *testReloader) rejected() string {
+	for i := len(tr.logs.AllEntries()) - 1; i >= 0; i-- {
+		if e := tr.logs.AllEntries()[i]; e.Level == logrus.ErrorLevel {
+			return e.Message
+		}
+	}
+	return ""
+}
+
+func TestReloader_AppliesReloadableChanges(t *testing.T) {
+	tr := newTestReloader(t, "db_url: postgres://db/a\nlog_level: info\n")
+	tr.write(t, "db_url: postgres://db/a\nlog_level: debug\n")
+	tr.reload("test")
+	if len(tr.applied) != 1 || tr.Config() != tr.applied[0] || tr.Config().LogLevel != "debug" {
+		t.Fatalf("applied %d configurations, LOG_LEVEL = %q, want the new configuration applied", len(tr.applied), tr.Config().LogLevel)
+	}
+	if msg := tr.rejected(); msg != "" {
+		t.Errorf("reload logged %q", msg)
+	}
+}
+
+func TestReloader_RejectsFixedSettings(t *testing.T) {
+	tr := newTestReloader(t, "db_url: postgres://db/a\nlog_level: info\n")
+	before := tr.Config()
+	tr.write(t, "db_url: postgres://db/b\nlog_level: debug\n")
+	tr.reload("test")
+	if tr.Config() != before || len(tr.applied) != 0 {
+		t.Errorf("a reload changing DB_URL was applied")
+	}
+	if msg := tr.rejected(); !strings.Contains(msg, "DB_URL cannot change without a restart") {
+		t.Errorf("reload logged %q, want DB_URL named", msg)
+	}
+}
+
+func TestReloader_KeepsConfigOnFailure(t *testing.T) {
+	tr := newTestReloader(t, "log_level: info\n")
+	before := tr.Config()
+
+	tr.write(t, "log_level: loud\n")
+	tr.reload("test")
+	if tr.Config() != before || len(tr.applied) != 0 {
+		t.Errorf("an invalid configuration was applied")
+	}
+	if msg := tr.rejected(); !strings.Contains(msg, "LOG_LEVEL") {
+		t.Errorf("reload logged %q, want the validation error", msg)
+	}
+
+	tr.write(t, "log_level: debug\n")
+	tr.applyErr = errors.New("cannot apply")
+	tr.reload("test")
+	if tr.Config() != before {
+		t.Errorf("a configuration that failed to apply replaced the one in effect")
+	}
+	if msg := tr.rejected(); !strings.Contains(msg, "cannot apply") {
+		t.Errorf("reload logged %q, want the error of apply", msg)
+	}
+}
+
+func TestReloader_RunDetectsFileChanges(t *testing.T) {
+	tr := newTestReloader(t, "log_level: info\n")
+	tr.pollInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tr.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// the file is read again by Run only once it changed, not on every tick
+	time.Sleep(20 * time.Millisecond)
+	if len(tr.logs.AllEntries()) != 0 {
+		t.Fatalf("unchanged file reloaded: %v", tr.logs.LastEntry().Message)
+	}
+	tr.write(t, "log_level: debug\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for tr.Config().LogLevel != "debug" {
+		if time.Now().After(deadline) {
+			t.Fatal("the change of the config file was not reloaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if e := tr.logs.LastEntry(); e.Data["reason"] != "config file changed" {
+		t.Errorf("reload reason = %v, want config file changed", e.Data["reason"])
+	}
+}
@@ -2,6 +2,8 @@ package dataprovider
 
 import (
 	"context"
+	"time"
+
 	"github.com/go-pg/pg/v10/orm"
 	"github.com/google/uuid"
 
@@ -10,8 +12,52 @@ import (
 
 type QueryHook func(query *orm.Query)
 
-type Provider interface {
+type strongConsistencyKey struct{}
+
+// WithStrongConsistency returns a copy of ctx whose reads see every completed write, e.g. by being served by the
+// primary database rather than a replica.
+func WithStrongConsistency(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strongConsistencyKey{}, true)
+}
+
+// StrongConsistency reports whether the reads of ctx must see every completed write.
+func StrongConsistency(ctx context.Context) bool {
+	strong, _ := ctx.Value(strongConsistencyKey{}).(bool)
+	return strong
+}
+
+// Monitor inspects the activity of a Postgres server. It only reads system views and never writes.
+type Monitor interface {
 	SlowQuery(ctx context.Context, req model.SlowQueriesRequest) ([]*model.SlowQueryRecord, error)
+	SlowQueryGroups(ctx context.Context, req model.SlowQueriesRequest) ([]*model.SlowQueryGroup, error)
+	// BlockingQueries returns every blocker/blocked edge matching the filter of req, ignoring its paging.
+	BlockingQueries(ctx context.Context, req model.BlockingQueriesRequest) ([]*model.BlockingQueryRecord, error)
+	Replication(ctx context.Context, req model.ReplicationRequest) ([]*model.ReplicationRecord, error)
+	WALReceivers(ctx context.Context, req model.ReplicationRequest) ([]*model.WALReceiverRecord, error)
+	ReplicationSlots(ctx context.Context, req model.ReplicationRequest) ([]*model.ReplicationSlotRecord, error)
+	TableHealth(ctx context.Context, req model.TableHealthRequest) ([]*model.TableHealthRecord, error)
+	IndexHealth(ctx context.Context, req model.TableHealthRequest) ([]*model.IndexHealthRecord, error)
+	BackendStates(ctx context.Context) ([]*model.BackendStateRecord, error)
+
+	Ping(ctx context.Context) error
+	PoolStats() model.PoolStats
+	// Close closes the connection pool, waiting for the queries in progress. The Monitor must not be used afterwards.
+	Close() error
+}
+
+type Provider interface {
+	Monitor
+
+	// CheckSchema fails unless the schema migrations of the service are applied.
+	CheckSchema(ctx context.Context) error
+
+	// SampleSlowQueries records every query running longer than threshold into the slow query history and
+	// returns the number of records inserted or updated.
+	SampleSlowQueries(ctx context.Context, threshold time.Duration) (int, error)
+	// PruneSlowQueryHistory deletes the slow query history last seen before the given time and returns the number
+	// of records deleted.
+	PruneSlowQueryHistory(ctx context.Context, before time.Time) (int, error)
+	SlowQueryHistory(ctx context.Context, req model.SlowQueryHistoryRequest) ([]*model.SlowQueryHistoryRecord, error)
 
 	Create(ctx context.Context, resource *model.Entry) (*model.Entry, error)
 	ListEntries(ctx context.Context, req model.ListEntriesRequest) ([]*model.Entry, error)
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/filters"
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/pkg/listing"
+)
+
+// blockingQuerySQL lists every blocked backend once per backend blocking it, together with the lock it is
+// waiting for and the modes the blocker holds on the same object.
+const blockingQuerySQL = `SELECT
+	blocked.datname AS database_name,
+	blocked.pid AS blocked_pid,
+	blocked.usename AS blocked_user_name,
+	blocked.state AS blocked_state,
+	blocked.query AS blocked_query,
+	COALESCE((EXTRACT(EPOCH FROM now() - blocked.query_start) * 1000)::bigint, 0) AS blocked_duration_ms,
+	waiting.locktype AS lock_type,
+	waiting.mode AS lock_mode,
+	waiting.relation::regclass::text AS relation_name,
+	blocking.pid AS blocking_pid,
+	blocking.usename AS blocking_user_name,
+	blocking.state AS blocking_state,
+	blocking.query AS blocking_query,
+	(
+		SELECT string_agg(DISTINCT held.mode, ',')
+		FROM pg_locks AS held
+		WHERE held.pid = blocking.pid
+			AND held.granted
+			AND held.locktype = waiting.locktype
+			AND held.database IS NOT DISTINCT FROM waiting.database
+			AND held.relation IS NOT DISTINCT FROM waiting.relation
+			AND held.transactionid IS NOT DISTINCT FROM waiting.transactionid
+	) AS blocking_lock_mode,
+	COALESCE((EXTRACT(EPOCH FROM now() - blocking.query_start) * 1000)::bigint, 0) AS blocking_duration_ms
+FROM pg_stat_activity AS blocked
+JOIN LATERAL (SELECT DISTINCT unnest(pg_blocking_pids(blocked.pid)) AS pid) AS blocker ON true
+JOIN pg_stat_activity AS blocking ON blocking.pid = blocker.pid
+LEFT JOIN pg_locks AS waiting ON waiting.pid = blocked.pid AND NOT waiting.granted`
+
+// BlockingQueries returns every edge matching the filter of req, ordered by its order. The paging of req applies to
+// the trees the edges are folded into, see model.PageBlockingTrees, so that no chain is cut at a page boundary.
+func (p PGRepository) BlockingQueries(ctx context.Context, req model.BlockingQueriesRequest) ([]*model.BlockingQueryRecord, error) {
+	var resources []*model.BlockingQueryRecord
+	if err := p.withTimeout(ctx, func(db orm.DB) error {
+		query := db.ModelContext(ctx, &model.BlockingQueryRecord{}).TableExpr("(?)", pg.Safe(blockingQuerySQL))
+		if err := listing.ApplyFilters(req.Filter, filters.BlockingQueries.Config, query); err != nil {
+			return fmt.Errorf("[blockingQueries] error in filter: %v", err)
+		}
+		return query.Order(req.OrderBy).Select(&resources)
+	}); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/filters"
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/pkg/listing"
+)
+
+// sampleSlowQueriesSQL copies every non-idle client backend running for longer than the threshold (in
+// milliseconds) into slow_query_history, extending the record of executions that were already sampled.
+const sampleSlowQueriesSQL = `INSERT INTO slow_query_history AS h
+	(pid, query_start, database_name, user_name, client_address, state, query, first_seen, last_seen, max_duration_ms, samples)
+SELECT
+	a.pid, a.query_start, a.datname, a.usename, a.client_addr::text, a.state, a.query, now(), now(),
+	(EXTRACT(EPOCH FROM now() - a.query_start) * 1000)::bigint, 1
+FROM pg_stat_activity AS a
+WHERE a.pid <> pg_backend_pid()
+	AND a.backend_type = 'client backend'
+	AND a.state <> 'idle'
+	AND a.query_start IS NOT NULL
+	AND now() - a.query_start > ? * interval '1 millisecond'
+ON CONFLICT (pid, query_start) DO UPDATE SET
+	state = EXCLUDED.state,
+	last_seen = EXCLUDED.last_seen,
+	max_duration_ms = GREATEST(h.max_duration_ms, EXCLUDED.max_duration_ms),
+	samples = h.samples + 1`
+
+func (p PGRepository) SampleSlowQueries(ctx context.Context, threshold time.Duration) (int, error) {
+	var n int
+	if err := p.runInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.ExecContext(ctx, sampleSlowQueriesSQL, threshold.Milliseconds())
+		if err != nil {
+			return err
+		}
+		n = res.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (p PGRepository) PruneSlowQueryHistory(ctx context.Context, before time.Time) (int, error) {
+	var n int
+	if err := p.runInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM slow_query_history WHERE last_seen < ?`, before)
+		if err != nil {
+			return err
+		}
+		n = res.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (p PGRepository) SlowQueryHistory(ctx context.Context, req model.SlowQueryHistoryRequest) ([]*model.SlowQueryHistoryRecord, error) {
+	var resources []*model.SlowQueryHistoryRecord
+	if err := p.withTimeout(ctx, func(db orm.DB) error {
+		query := db.ModelContext(ctx, &model.SlowQueryHistoryRecord{})
+		if err := listing.ApplyFilters(req.Filter, filters.SlowQueryHistory.Config, query); err != nil {
+			return fmt.Errorf("[slowQueryHistory] error in filter: %v", err)
+		}
+		if !req.From.IsZero() {
+			query.Where("?TableAlias.last_seen >= ?", req.From)
+		}
+		if !req.To.IsZero() {
+			query.Where("?TableAlias.first_seen <= ?", req.To)
+		}
+		return p.paginate(query, req.OrderBy, req.PageSize, req.PageOffset).Select(&resources)
+	}); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
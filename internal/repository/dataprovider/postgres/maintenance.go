@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/filters"
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+)
+
+const tableHealthSQL = `SELECT
+	t.schemaname AS schema_name,
+	t.relname AS table_name,
+	t.n_live_tup AS live_tuples,
+	t.n_dead_tup AS dead_tuples,
+	COALESCE(t.n_dead_tup::float8 / NULLIF(t.n_live_tup + t.n_dead_tup, 0), 0) AS dead_tuple_ratio,
+	t.n_mod_since_analyze AS mods_since_analyze,
+	t.seq_scan,
+	t.seq_tup_read,
+	COALESCE(t.idx_scan, 0) AS idx_scan,
+	COALESCE(t.idx_tup_fetch, 0) AS idx_tup_fetch,
+	COALESCE(t.idx_scan::float8 / NULLIF(t.seq_scan + t.idx_scan, 0), 0) AS index_scan_ratio,
+	t.last_vacuum::text AS last_vacuum,
+	t.last_autovacuum::text AS last_autovacuum,
+	t.last_analyze::text AS last_analyze,
+	t.last_autoanalyze::text AS last_autoanalyze,
+	t.vacuum_count,
+	t.autovacuum_count,
+	t.analyze_count,
+	t.autoanalyze_count,
+	pg_total_relation_size(t.relid) AS total_bytes,
+	pg_relation_size(t.relid) AS table_bytes,
+	pg_indexes_size(t.relid) AS index_bytes
+FROM pg_stat_user_tables AS t`
+
+// indexHealthSQL compares every index with the other indexes of its table: an index is a duplicate when another
+// one has the same columns, operator classes, expressions and predicate, and it is covered by plain indexes whose
+// columns start with all of its columns.
+const indexHealthSQL = `SELECT
+	i.schemaname AS schema_name,
+	i.relname AS table_name,
+	i.indexrelname AS index_name,
+	pg_get_indexdef(i.indexrelid) AS definition,
+	i.idx_scan,
+	i.idx_tup_read,
+	i.idx_tup_fetch,
+	pg_relation_size(i.indexrelid) AS index_bytes,
+	x.indisunique AS is_unique,
+	x.indisprimary AS is_primary,
+	(i.idx_scan = 0 AND NOT x.indisunique AND NOT x.indisprimary) AS unused,
+	dup.names IS NOT NULL AS duplicate,
+	dup.names AS duplicate_of,
+	cov.names AS covered_by
+FROM pg_stat_user_indexes AS i
+JOIN pg_index AS x ON x.indexrelid = i.indexrelid
+LEFT JOIN LATERAL (
+	SELECT string_agg(o.indexrelid::regclass::text, ',' ORDER BY o.indexrelid::regclass::text) AS names
+	FROM pg_index AS o
+	WHERE o.indrelid = x.indrelid
+		AND o.indexrelid <> x.indexrelid
+		AND o.indkey::text = x.indkey::text
+		AND o.indclass::text = x.indclass::text
+		AND COALESCE(pg_get_expr(o.indexprs, o.indrelid), '') = COALESCE(pg_get_expr(x.indexprs, x.indrelid), '')
+		AND COALESCE(pg_get_expr(o.indpred, o.indrelid), '') = COALESCE(pg_get_expr(x.indpred, x.indrelid), '')
+) AS dup ON true
+LEFT JOIN LATERAL (
+	SELECT string_agg(o.indexrelid::regclass::text, ',' ORDER BY o.indexrelid::regclass::text) AS names
+	FROM pg_index AS o
+	WHERE o.indrelid = x.indrelid
+		AND NOT x.indisunique
+		AND x.indexprs IS NULL AND x.indpred IS NULL
+		AND o.indexprs IS NULL AND o.indpred IS NULL
+		AND o.indkey::text LIKE x.indkey::text || ' %'
+) AS cov ON true`
+
+func (p PGRepository) TableHealth(ctx context.Context, req model.TableHealthRequest) ([]*model.TableHealthRecord, error) {
+	var resources []*model.TableHealthRecord
+	if err := p.selectView(ctx, &resources, tableHealthSQL, filters.Tables.Config, req.ListParams); err != nil {
+		return nil, fmt.Errorf("[tableHealth] %w", err)
+	}
+	return resources, nil
+}
+
+func (p PGRepository) IndexHealth(ctx context.Context, req model.TableHealthRequest) ([]*model.IndexHealthRecord, error) {
+	var resources []*model.IndexHealthRecord
+	if err := p.selectView(ctx, &resources, indexHealthSQL, filters.Indexes.Config, req.ListParams); err != nil {
+		return nil, fmt.Errorf("[indexHealth] %w", err)
+	}
+	return resources, nil
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/metrics"
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+)
+
+// backendStatesSQL counts client backends by state. Waiting backends wait for a lock and the longest query is the
+// longest running active one.
+const backendStatesSQL = `SELECT
+	COALESCE(state, 'unknown') AS state,
+	count(*) AS backends,
+	count(*) FILTER (WHERE wait_event_type = 'Lock') AS waiting_backends,
+	COALESCE(max(EXTRACT(EPOCH FROM now() - query_start)) FILTER (WHERE state = 'active'), 0)::float8 AS longest_query_seconds
+FROM pg_stat_activity
+WHERE backend_type = 'client backend'
+GROUP BY 1`
+
+func (p PGRepository) BackendStates(ctx context.Context) ([]*model.BackendStateRecord, error) {
+	var resources []*model.BackendStateRecord
+	if err := p.withTimeout(ctx, func(db orm.DB) error {
+		_, err := db.QueryContext(ctx, &resources, backendStatesSQL)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("[backendStates] %w", err)
+	}
+	return resources, nil
+}
+
+// queryMetrics records the duration of every query run on database.
+type queryMetrics struct {
+	database string
+}
+
+func (m queryMetrics) BeforeQuery(ctx context.Context, q *pg.QueryEvent) (context.Context, error) {
+	return ctx, nil
+}
+
+func (m queryMetrics) AfterQuery(ctx context.Context, q *pg.QueryEvent) error {
+	err := q.Err
+	if err == pg.ErrNoRows {
+		err = nil
+	}
+	metrics.ObserveQuery(m.database, queryOperation(q), time.Since(q.StartTime), err)
+	return nil
+}
+
+// queryOperation returns the lowercase SQL command of q, e.g. "select".
+func queryOperation(q *pg.QueryEvent) string {
+	if cmd, ok := q.Query.(orm.QueryCommand); ok {
+		return strings.ToLower(string(cmd.Operation()))
+	}
+	query, err := q.UnformattedQuery()
+	if err != nil {
+		return "unknown"
+	}
+	if fields := strings.Fields(string(query)); len(fields) > 0 {
+		return strings.ToLower(fields[0])
+	}
+	return "unknown"
+}
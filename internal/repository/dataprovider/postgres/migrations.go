@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"embed"
+	"io/fs"
+
+	pg "github.com/go-pg/pg/v10"
+
+	"github.com/rahul2393/city-falcon-assignment/pkg/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+func newMigrator(db *pg.DB) (*migrate.Migrator, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, files)
+}
+
+// NewMigrator connects to dbURL to manage the schema migrations of the service. Close closes the connection.
+func NewMigrator(dbURL string) (*migrate.Migrator, error) {
+	db, err := connect(Options{URL: dbURL}, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db)
+}
@@ -0,0 +1,11 @@
+-- Table created by CreateTable(IfNotExists) before migrations existed. IF NOT EXISTS adopts it unchanged on
+-- databases that already have it, whose data this migration does not own: it has no down migration, so that
+-- migrate down never drops it.
+CREATE TABLE IF NOT EXISTS "entries" (
+	"id" uuid,
+	"create_time" timestamptz NOT NULL,
+	"update_time" timestamptz NOT NULL,
+	"delete_time" timestamptz,
+	"version" bigint NOT NULL DEFAULT 1,
+	PRIMARY KEY ("id")
+);
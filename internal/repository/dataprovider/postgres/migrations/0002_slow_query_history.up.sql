@@ -0,0 +1,16 @@
+-- Queries recorded by the slow query sampler. IF NOT EXISTS adopts the table created by CreateTable(IfNotExists)
+-- before migrations existed.
+CREATE TABLE IF NOT EXISTS "slow_query_history" (
+	"pid" integer,
+	"query_start" timestamptz,
+	"database_name" text,
+	"user_name" text,
+	"client_address" text,
+	"state" text,
+	"query" text,
+	"first_seen" timestamptz NOT NULL,
+	"last_seen" timestamptz NOT NULL,
+	"max_duration_ms" bigint NOT NULL,
+	"samples" bigint NOT NULL DEFAULT 1,
+	PRIMARY KEY ("pid", "query_start")
+);
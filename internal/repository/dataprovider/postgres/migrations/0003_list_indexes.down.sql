@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "slow_query_history_last_seen_idx";
+DROP INDEX IF EXISTS "entries_create_time_idx";
@@ -0,0 +1,3 @@
+-- Default orderings of GET /entries and GET /slow-queries/history.
+CREATE INDEX IF NOT EXISTS "entries_create_time_idx" ON "entries" ("create_time");
+CREATE INDEX IF NOT EXISTS "slow_query_history_last_seen_idx" ON "slow_query_history" ("last_seen");
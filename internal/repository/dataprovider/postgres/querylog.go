@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/requestlog"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+// QueryLogOptions selects the queries logged by a repository.
+type QueryLogOptions struct {
+	// SlowThreshold, when positive, logs the queries taking at least as long at warn level.
+	SlowThreshold time.Duration
+	// SampleRate is the fraction of the other queries logged at debug level: 0 logs none, 1 all of them.
+	SampleRate float64
+}
+
+func (o QueryLogOptions) enabled() bool {
+	return o.SlowThreshold > 0 || o.SampleRate > 0
+}
+
+// QueryLog holds the QueryLogOptions of the repositories sharing it. They may be changed with Set while the
+// repositories run.
+type QueryLog struct {
+	options atomic.Pointer[QueryLogOptions]
+}
+
+// NewQueryLog returns a QueryLog starting with options.
+func NewQueryLog(options QueryLogOptions) *QueryLog {
+	l := &QueryLog{}
+	l.Set(options)
+	return l
+}
+
+// Set replaces the options, applying to the queries completing from now on.
+func (l *QueryLog) Set(options QueryLogOptions) {
+	l.options.Store(&options)
+}
+
+// Options returns the current options. A nil QueryLog logs no query.
+func (l *QueryLog) Options() QueryLogOptions {
+	if l == nil {
+		return QueryLogOptions{}
+	}
+	return *l.options.Load()
+}
+
+type dbLogger struct {
+	log      *logrus.Entry
+	redactor *redact.Redactor
+	queryLog *QueryLog
+}
+
+func (d dbLogger) BeforeQuery(ctx context.Context, q *pg.QueryEvent) (context.Context, error) {
+	return ctx, nil
+}
+
+func (d dbLogger) AfterQuery(ctx context.Context, q *pg.QueryEvent) error {
+	options := d.queryLog.Options()
+	if !options.enabled() {
+		return nil
+	}
+	duration := time.Since(q.StartTime)
+	slow := options.SlowThreshold > 0 && duration >= options.SlowThreshold
+	if !slow && (options.SampleRate <= 0 || options.SampleRate < 1 && rand.Float64() >= options.SampleRate) {
+		return nil
+	}
+	bytes, err := q.FormattedQuery()
+	if err != nil {
+		return nil
+	}
+
+	log := d.log.WithFields(logrus.Fields{
+		"duration_ms": duration.Milliseconds(),
+		"operation":   callerOperation(),
+	})
+	if id := requestlog.RequestID(ctx); id != "" {
+		log = log.WithField("request_id", id)
+	}
+	if q.Result != nil {
+		log = log.WithFields(logrus.Fields{
+			"rows_returned": q.Result.RowsReturned(),
+			"rows_affected": q.Result.RowsAffected(),
+		})
+	}
+	if q.Err != nil && q.Err != pg.ErrNoRows {
+		log = log.WithError(q.Err)
+	}
+	if slow {
+		log.Warn("slow query: " + d.redactor.Redact(string(bytes)))
+		return nil
+	}
+	log.Debug(d.redactor.Redact(string(bytes)))
+	return nil
+}
+
+// callerOperation returns the name of the outermost PGRepository method on the stack, e.g. "ListEntries", which
+// is the operation the service asked for even when the query is issued by a helper or a transaction closure.
+func callerOperation() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	operation := "unknown"
+	for {
+		frame, more := frames.Next()
+		if name := repositoryMethod(frame.Function); name != "" {
+			operation = name
+		}
+		if !more {
+			return operation
+		}
+	}
+}
+
+// repositoryMethod returns the method of PGRepository function belongs to, or an empty string.
+func repositoryMethod(function string) string {
+	for _, receiver := range []string{".PGRepository.", ".(*PGRepository)."} {
+		if i := strings.Index(function, receiver); i >= 0 {
+			name := function[i+len(receiver):]
+			if j := strings.IndexByte(name, '.'); j >= 0 {
+				name = name[:j]
+			}
+			return name
+		}
+	}
+	return ""
+}
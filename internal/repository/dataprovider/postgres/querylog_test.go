@@ -0,0 +1,45 @@
+package postgres
+
+import "testing"
+
+func TestRepositoryMethod(t *testing.T) {
+	tests := []struct {
+		function string
+		want     string
+	}{
+		{"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider/postgres.PGRepository.ListEntries", "ListEntries"},
+		{"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider/postgres.PGRepository.Update.func1", "Update"},
+		{"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider/postgres.(*PGRepository).Delete", "Delete"},
+		{"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider/postgres.dbLogger.AfterQuery", ""},
+		{"main.main", ""},
+	}
+	for _, tt := range tests {
+		if got := repositoryMethod(tt.function); got != tt.want {
+			t.Errorf("repositoryMethod(%q) = %q, want %q", tt.function, got, tt.want)
+		}
+	}
+}
+
+// afterQuery stands for the query hook calling callerOperation.
+func afterQuery() string {
+	return callerOperation()
+}
+
+func (p PGRepository) testOperation() string {
+	return p.testHelper()
+}
+
+func (p PGRepository) testHelper() string {
+	return func() string {
+		return afterQuery()
+	}()
+}
+
+func TestCallerOperation(t *testing.T) {
+	if got := (PGRepository{}).testOperation(); got != "testOperation" {
+		t.Errorf("callerOperation() = %q, want the outermost repository method testOperation", got)
+	}
+	if got := afterQuery(); got != "unknown" {
+		t.Errorf("callerOperation() outside of the repository = %q, want unknown", got)
+	}
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+const (
+	defaultReplicaCheckInterval = 5 * time.Second
+	// replicaPingTimeout bounds each health check of a replica.
+	replicaPingTimeout = 2 * time.Second
+)
+
+// replica is a read-only copy of the primary database.
+type replica struct {
+	db      *pg.DB
+	addr    string
+	healthy atomic.Bool
+}
+
+// replicaSet spreads reads over the healthy replicas round-robin. A replica failing a health check, or a read with
+// a connection error, is ejected until a health check succeeds again.
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	logger   *logrus.Entry
+	stop     context.CancelFunc
+	done     sync.WaitGroup
+}
+
+// newReplicaSet connects to the replicas at urls and checks their health every interval until close is called.
+// Unreachable replicas do not fail the call: they are ejected until they recover.
+func newReplicaSet(urls []string, opts Options, queryLog *QueryLog, logger *logrus.Entry, redactor *redact.Redactor) (*replicaSet, error) {
+	s := &replicaSet{logger: logger.WithField("component", "replicas")}
+	for _, url := range urls {
+		replicaOpts := opts
+		replicaOpts.URL = url
+		db, err := connect(replicaOpts, queryLog, logger, redactor)
+		if err != nil {
+			s.closeReplicas()
+			return nil, err
+		}
+		s.replicas = append(s.replicas, &replica{db: db, addr: db.Options().Addr})
+	}
+	s.check(context.Background())
+
+	interval := opts.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = defaultReplicaCheckInterval
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	s.stop = stop
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.check(ctx)
+			}
+		}
+	}()
+	return s, nil
+}
+
+// check pings every replica, ejecting those failing and restoring those succeeding.
+func (s *replicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := r.db.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			s.eject(r, err)
+		} else if !r.healthy.Swap(true) {
+			s.logger.WithField("replica", r.addr).Info("replica is healthy, routing reads to it")
+		}
+	}
+}
+
+// eject stops routing reads to r until it passes a health check.
+func (s *replicaSet) eject(r *replica, err error) {
+	if r.healthy.Swap(false) {
+		s.logger.WithField("replica", r.addr).Warnf("replica ejected: %v", err)
+	}
+}
+
+// pick returns the next healthy replica, nil when there is none. The reads of an ejected replica are spread evenly
+// over the others.
+func (s *replicaSet) pick() *replica {
+	healthy := make([]*replica, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	return healthy[s.next.Add(1)%uint64(len(healthy))]
+}
+
+// close stops the health checks and closes the connection pools of the replicas.
+func (s *replicaSet) close() error {
+	s.stop()
+	s.done.Wait()
+	return s.closeReplicas()
+}
+
+func (s *replicaSet) closeReplicas() error {
+	var errs []error
+	for _, r := range s.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// read runs fn like withTimeout, on a replica unless ctx asks for strong consistency or no replica is healthy. A
+// replica failing with a connection error is ejected and fn is run again on the primary, so fn must reset whatever
+// it selects into.
+func (p PGRepository) read(ctx context.Context, fn func(db orm.DB) error) error {
+	if p.replicas == nil || dataprovider.StrongConsistency(ctx) {
+		return p.withTimeout(ctx, fn)
+	}
+	r := p.replicas.pick()
+	if r == nil {
+		return p.withTimeout(ctx, fn)
+	}
+	// a replica failing is not retried, the primary answers instead
+	onReplica := p
+	onReplica.db = r.db
+	onReplica.retry.maxRetries = 0
+	err := onReplica.withTimeout(ctx, fn)
+	if err == nil || !retryableRead(err) || ctx.Err() != nil {
+		return err
+	}
+	p.replicas.eject(r, err)
+	return p.withTimeout(ctx, fn)
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+)
+
+// newTestReplicaRepository returns a repository over unreachable databases, which is enough as long as the queries
+// only record the database they run on. The replicas start healthy.
+func newTestReplicaRepository(t *testing.T, replicaAddrs ...string) *PGRepository {
+	t.Helper()
+	repo := newPGRepository(pg.Connect(&pg.Options{Addr: "primary:5432"}), Options{})
+	repo.replicas = &replicaSet{logger: logrus.NewEntry(logrus.New())}
+	for _, addr := range replicaAddrs {
+		r := &replica{db: pg.Connect(&pg.Options{Addr: addr}), addr: addr}
+		r.healthy.Store(true)
+		repo.replicas.replicas = append(repo.replicas.replicas, r)
+	}
+	t.Cleanup(func() {
+		repo.replicas.closeReplicas()
+		repo.db.Close()
+	})
+	return repo
+}
+
+// readAddr returns the address of the database serving a read of ctx.
+func readAddr(t *testing.T, repo *PGRepository, ctx context.Context) string {
+	t.Helper()
+	var addr string
+	if err := repo.read(ctx, func(db orm.DB) error {
+		addr = db.(*pg.DB).Options().Addr
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+func TestRead_RoundRobinOverHealthyReplicas(t *testing.T) {
+	repo := newTestReplicaRepository(t, "a:5432", "b:5432", "c:5432")
+	repo.replicas.replicas[1].healthy.Store(false)
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		seen[readAddr(t, repo, context.Background())]++
+	}
+	if seen["a:5432"] != 2 || seen["c:5432"] != 2 {
+		t.Errorf("reads = %v, want 2 on each healthy replica", seen)
+	}
+
+	repo.replicas.replicas[0].healthy.Store(false)
+	repo.replicas.replicas[2].healthy.Store(false)
+	if got := readAddr(t, repo, context.Background()); got != "primary:5432" {
+		t.Errorf("read without healthy replica on %s, want the primary", got)
+	}
+}
+
+func TestRead_StrongConsistency(t *testing.T) {
+	repo := newTestReplicaRepository(t, "a:5432")
+	if got := readAddr(t, repo, dataprovider.WithStrongConsistency(context.Background())); got != "primary:5432" {
+		t.Errorf("strongly consistent read on %s, want the primary", got)
+	}
+	if got := readAddr(t, repo, context.Background()); got != "a:5432" {
+		t.Errorf("read on %s, want the replica", got)
+	}
+}
+
+func TestRead_EjectsFailingReplica(t *testing.T) {
+	repo := newTestReplicaRepository(t, "a:5432")
+	var addrs []string
+	if err := repo.read(context.Background(), func(db orm.DB) error {
+		addr := db.(*pg.DB).Options().Addr
+		addrs = append(addrs, addr)
+		if addr == "a:5432" {
+			return io.EOF
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[1] != "primary:5432" {
+		t.Errorf("read ran on %v, want the replica then the primary", addrs)
+	}
+	if repo.replicas.replicas[0].healthy.Load() {
+		t.Error("replica still healthy after a connection error")
+	}
+}
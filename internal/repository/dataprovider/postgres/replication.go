@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/filters"
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+)
+
+// currentLSNSQL is the latest WAL location of the server, which on a standby is the last location received.
+const currentLSNSQL = `CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_lsn() END`
+
+const replicationSQL = `SELECT
+	r.pid,
+	r.usename AS user_name,
+	r.application_name,
+	r.client_addr::text AS client_address,
+	r.backend_start::text AS backend_start,
+	r.state,
+	r.sync_state,
+	r.sent_lsn::text AS sent_lsn,
+	r.write_lsn::text AS write_lsn,
+	r.flush_lsn::text AS flush_lsn,
+	r.replay_lsn::text AS replay_lsn,
+	COALESCE((EXTRACT(EPOCH FROM r.write_lag) * 1000)::bigint, 0) AS write_lag_ms,
+	COALESCE((EXTRACT(EPOCH FROM r.flush_lag) * 1000)::bigint, 0) AS flush_lag_ms,
+	COALESCE((EXTRACT(EPOCH FROM r.replay_lag) * 1000)::bigint, 0) AS replay_lag_ms,
+	COALESCE(pg_wal_lsn_diff(` + currentLSNSQL + `, r.replay_lsn), 0)::bigint AS replay_lag_bytes,
+	s.slot_name
+FROM pg_stat_replication AS r
+LEFT JOIN pg_replication_slots AS s ON s.active_pid = r.pid`
+
+// walReceiverSQL selects the WAL receivers, with the written and flushed LSNs replaced by received_lsn before
+// Postgres 13.
+const walReceiverSQL = `SELECT
+	w.pid,
+	w.status,
+	w.sender_host,
+	w.sender_port,
+	w.slot_name,
+	w.receive_start_lsn::text AS receive_start_lsn,
+	%s
+	pg_last_wal_replay_lsn()::text AS replay_lsn,
+	w.last_msg_receipt_time::text AS last_msg_receipt_time,
+	w.latest_end_time::text AS latest_end_time,
+	COALESCE((EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) * 1000)::bigint, 0) AS replay_delay_ms,
+	COALESCE(pg_wal_lsn_diff(%s, pg_last_wal_replay_lsn()), 0)::bigint AS replay_lag_bytes
+FROM pg_stat_wal_receiver AS w`
+
+// replicationSlotsSQL selects the replication slots, with a null wal_status before Postgres 13.
+const replicationSlotsSQL = `SELECT
+	slot_name,
+	plugin,
+	slot_type,
+	database,
+	temporary,
+	active,
+	active_pid,
+	restart_lsn::text AS restart_lsn,
+	confirmed_flush_lsn::text AS confirmed_flush_lsn,
+	%s,
+	COALESCE(pg_wal_lsn_diff(` + currentLSNSQL + `, restart_lsn), 0)::bigint AS retained_bytes
+FROM pg_replication_slots`
+
+// walStatusVersion is the server_version_num of Postgres 13, which added pg_replication_slots.wal_status and
+// split pg_stat_wal_receiver.received_lsn into written_lsn and flushed_lsn.
+const walStatusVersion = 130000
+
+// serverVersion returns the server_version_num of the server.
+func (p PGRepository) serverVersion(ctx context.Context) (int, error) {
+	var version int
+	err := p.withTimeout(ctx, func(db orm.DB) error {
+		_, err := db.QueryOneContext(ctx, pg.Scan(&version), "SELECT current_setting('server_version_num')::int")
+		return err
+	})
+	return version, err
+}
+
+func (p PGRepository) Replication(ctx context.Context, req model.ReplicationRequest) ([]*model.ReplicationRecord, error) {
+	var resources []*model.ReplicationRecord
+	if err := p.selectView(ctx, &resources, replicationSQL, filters.Replication.Config, req.ListParams); err != nil {
+		return nil, fmt.Errorf("[replication] %w", err)
+	}
+	return resources, nil
+}
+
+func (p PGRepository) WALReceivers(ctx context.Context, req model.ReplicationRequest) ([]*model.WALReceiverRecord, error) {
+	version, err := p.serverVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[walReceivers] %w", err)
+	}
+	view := fmt.Sprintf(walReceiverSQL, "w.written_lsn::text AS written_lsn,\n\tw.flushed_lsn::text AS flushed_lsn,", "w.flushed_lsn")
+	if version < walStatusVersion {
+		view = fmt.Sprintf(walReceiverSQL, "NULL::text AS written_lsn,\n\tw.received_lsn::text AS flushed_lsn,", "w.received_lsn")
+	}
+	var resources []*model.WALReceiverRecord
+	if err := p.selectView(ctx, &resources, view, filters.ReplicationReceivers.Config, req.ListParams); err != nil {
+		return nil, fmt.Errorf("[walReceivers] %w", err)
+	}
+	return resources, nil
+}
+
+func (p PGRepository) ReplicationSlots(ctx context.Context, req model.ReplicationRequest) ([]*model.ReplicationSlotRecord, error) {
+	version, err := p.serverVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[replicationSlots] %w", err)
+	}
+	view := fmt.Sprintf(replicationSlotsSQL, "wal_status")
+	if version < walStatusVersion {
+		view = fmt.Sprintf(replicationSlotsSQL, "NULL::text AS wal_status")
+	}
+	var resources []*model.ReplicationSlotRecord
+	if err := p.selectView(ctx, &resources, view, filters.ReplicationSlots.Config, req.ListParams); err != nil {
+		return nil, fmt.Errorf("[replicationSlots] %w", err)
+	}
+	return resources, nil
+}
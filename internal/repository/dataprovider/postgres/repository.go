@@ -3,91 +3,282 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
 	pg "github.com/go-pg/pg/v10"
 	"github.com/go-pg/pg/v10/orm"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/rahul2393/city-falcon-assignment/internal/filters"
 	"github.com/rahul2393/city-falcon-assignment/internal/model"
 	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
 	"github.com/rahul2393/city-falcon-assignment/pkg/listing"
+	"github.com/rahul2393/city-falcon-assignment/pkg/querynorm"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
 )
 
-const (
-	defaultLimit = 100
-)
+// defaultMaxPageSize caps the page size when Options.MaxPageSize is not set.
+const defaultMaxPageSize = 100
+
+// slowQuerySQL exposes pg_stat_activity together with the running time of the current query.
+const slowQuerySQL = `SELECT *, COALESCE((EXTRACT(EPOCH FROM now() - query_start) * 1000)::bigint, 0) AS duration_ms
+FROM pg_stat_activity`
 
-var entryConfig = listing.FilterConfig{
-	Hooks: map[string]listing.FilterHook{
-		"database_name": listing.RenameFieldAndMapValuesFilterHook("datname", func(value interface{}) (interface{}, error) {
-			return value.(string), nil
-		}),
-	},
+// Options configures the connection to a database.
+type Options struct {
+	URL string
+	// PoolSize is the maximum number of connections, 0 for 10 per CPU.
+	PoolSize int
+	// MinIdleConns is the number of idle connections kept open.
+	MinIdleConns int
+	// DialTimeout, ReadTimeout and WriteTimeout bound the socket operations, 0 for the defaults of go-pg: 5s to
+	// dial, no read or write timeout.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// MaxRetries is the number of retries of the transactions and reads failing with a transient error.
+	MaxRetries int
+	// MinRetryBackoff and MaxRetryBackoff bound the exponential backoff between retries, 0 for 250ms and 4s.
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+	// MaxPageSize caps the page size of the list methods, 0 for defaultMaxPageSize.
+	MaxPageSize int
+	// ReplicaURLs are the URLs of read replicas of the database serving ListEntries and GetByID, connected with
+	// the other options. NewMonitor ignores them.
+	ReplicaURLs []string
+	// ReplicaCheckInterval is how often the health of the replicas is checked, 0 for 5s.
+	ReplicaCheckInterval time.Duration
 }
 
 type PGRepository struct {
-	db *pg.DB
+	db          *pg.DB
+	maxPageSize int
+	retry       retryPolicy
+	// replicas is nil without replicas
+	replicas *replicaSet
 }
 
-func NewRepository(dbURL string, enableQueryLog bool, logger *logrus.Entry) (dataprovider.Provider, error) {
-	dbopts, err := pg.ParseURL(dbURL)
+// NewRepository connects to the database of opts. When migrate is set the pending schema migrations are applied, otherwise they
+// must have been applied beforehand, e.g. with the migrate command. Queries are logged as selected by queryLog, which
+// may be nil to log none, after being passed through redactor, which may be nil to log queries verbatim.
+func NewRepository(opts Options, migrate bool, queryLog *QueryLog, logger *logrus.Entry, redactor *redact.Redactor) (dataprovider.Provider, error) {
+	db, err := connect(opts, queryLog, logger, redactor)
 	if err != nil {
-		return nil, fmt.Errorf("pg.ParseURL(): %w", err)
+		return nil, err
 	}
-	db := pg.Connect(dbopts)
 	if err := db.Ping(context.Background()); err != nil {
 		return nil, fmt.Errorf("db.Ping(): %w", err)
 	}
-	if err := db.Model(&model.Entry{}).CreateTable(&orm.CreateTableOptions{
-		IfNotExists: true,
-	}); err != nil {
+	if migrate {
+		migrator, err := newMigrator(db)
+		if err != nil {
+			return nil, err
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("migrator.Up(): %w", err)
+		}
+		for _, m := range applied {
+			logger.Infof("applied schema migration %d_%s", m.Version, m.Name)
+		}
+	}
+	repo := newPGRepository(db, opts)
+	if len(opts.ReplicaURLs) > 0 {
+		if repo.replicas, err = newReplicaSet(opts.ReplicaURLs, opts, queryLog, logger, redactor); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+	}
+	return repo, nil
+}
+
+// NewMonitor returns a Monitor for the server of opts. Unlike NewRepository it neither requires the server to be
+// reachable nor creates any table, so that an unavailable target does not prevent the service from starting.
+func NewMonitor(opts Options, queryLog *QueryLog, logger *logrus.Entry, redactor *redact.Redactor) (dataprovider.Monitor, error) {
+	db, err := connect(opts, queryLog, logger, redactor)
+	if err != nil {
 		return nil, err
 	}
-	if enableQueryLog {
-		db.AddQueryHook(dbLogger{log: logger})
+	return newPGRepository(db, opts), nil
+}
+
+func newPGRepository(db *pg.DB, opts Options) *PGRepository {
+	maxPageSize := opts.MaxPageSize
+	if maxPageSize <= 0 {
+		maxPageSize = defaultMaxPageSize
+	}
+	retry := retryPolicy{maxRetries: opts.MaxRetries, minBackoff: opts.MinRetryBackoff, maxBackoff: opts.MaxRetryBackoff}
+	if retry.minBackoff <= 0 {
+		retry.minBackoff = defaultMinRetryBackoff
+	}
+	if retry.maxBackoff <= 0 {
+		retry.maxBackoff = defaultMaxRetryBackoff
+	}
+	return &PGRepository{
+		db:          db,
+		maxPageSize: maxPageSize,
+		retry:       retry,
 	}
-	return &PGRepository{db: db}, nil
 }
 
-type dbLogger struct {
-	log *logrus.Entry
+func connect(opts Options, queryLog *QueryLog, logger *logrus.Entry, redactor *redact.Redactor) (*pg.DB, error) {
+	dbopts, err := pg.ParseURL(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("pg.ParseURL(): %w", err)
+	}
+	if opts.PoolSize > 0 {
+		dbopts.PoolSize = opts.PoolSize
+	}
+	dbopts.MinIdleConns = opts.MinIdleConns
+	if opts.DialTimeout > 0 {
+		dbopts.DialTimeout = opts.DialTimeout
+	}
+	dbopts.ReadTimeout = opts.ReadTimeout
+	dbopts.WriteTimeout = opts.WriteTimeout
+	// the repository retries the transactions and reads failing with a transient error, retries of the pool would
+	// multiply with them
+	dbopts.MaxRetries = 0
+	db := pg.Connect(dbopts)
+	db.AddQueryHook(queryMetrics{database: dbopts.Addr + "/" + dbopts.Database})
+	db.AddQueryHook(queryTracer{database: dbopts.Database, redactor: redactor})
+	if queryLog != nil {
+		db.AddQueryHook(dbLogger{log: logger, redactor: redactor, queryLog: queryLog})
+	}
+	return db, nil
+}
+
+func (p PGRepository) Ping(ctx context.Context) error {
+	return p.db.Ping(ctx)
 }
 
-func (d dbLogger) BeforeQuery(ctx context.Context, q *pg.QueryEvent) (context.Context, error) {
-	return ctx, nil
+func (p PGRepository) Close() error {
+	if p.replicas != nil {
+		if err := p.replicas.close(); err != nil {
+			p.db.Close()
+			return err
+		}
+	}
+	return p.db.Close()
 }
 
-func (d dbLogger) AfterQuery(ctx context.Context, q *pg.QueryEvent) error {
-	bytes, err := q.FormattedQuery()
-	if err == nil {
-		d.log.Debug(string(bytes))
+func (p PGRepository) PoolStats() model.PoolStats {
+	stats := p.db.PoolStats()
+	return model.PoolStats{
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Timeouts:   stats.Timeouts,
+		TotalConns: stats.TotalConns,
+		IdleConns:  stats.IdleConns,
+		StaleConns: stats.StaleConns,
 	}
+}
 
+// CheckSchema fails unless every schema migration is applied.
+func (p PGRepository) CheckSchema(ctx context.Context) error {
+	migrator, err := newMigrator(p.db)
+	if err != nil {
+		return err
+	}
+	if err := migrator.Verify(ctx); err != nil {
+		return fmt.Errorf("[checkSchema] %w", err)
+	}
 	return nil
 }
 
+// paginate applies ordering and a page window to query, capping the page size at the maximum of the repository.
+func (p PGRepository) paginate(query *orm.Query, orderBy string, pageSize, pageOffset int) *orm.Query {
+	query.Order(orderBy)
+	if pageSize > p.maxPageSize {
+		pageSize = p.maxPageSize
+	}
+	if pageOffset < 0 {
+		pageOffset = 0
+	}
+	return query.Limit(pageSize).Offset(pageOffset)
+}
+
+// selectView selects the rows of the SQL view into resources, a pointer to a slice of models, applying the
+// filter of req with config, its ordering and paging.
+func (p PGRepository) selectView(ctx context.Context, resources interface{}, view string, config listing.FilterConfig, req model.ListParams) error {
+	return p.withTimeout(ctx, func(db orm.DB) error {
+		query := db.ModelContext(ctx, resources).TableExpr("(?)", pg.Safe(view))
+		if err := listing.ApplyFilters(req.Filter, config, query); err != nil {
+			return fmt.Errorf("error in filter: %v", err)
+		}
+		return p.paginate(query, req.OrderBy, req.PageSize, req.PageOffset).Select()
+	})
+}
+
 func (p PGRepository) SlowQuery(ctx context.Context, req model.SlowQueriesRequest) ([]*model.SlowQueryRecord, error) {
 	var resources []*model.SlowQueryRecord
-	query := p.db.ModelContext(ctx, &model.SlowQueryRecord{})
-	if err := listing.ApplyFilters(req.Filter, entryConfig, query); err != nil {
-		return nil, fmt.Errorf("[slowQuery] error in filter: %v", err)
+	if err := p.withTimeout(ctx, func(db orm.DB) error {
+		query, err := slowQueryQuery(ctx, db, req)
+		if err != nil {
+			return err
+		}
+		return p.paginate(query, req.OrderBy, req.PageSize, req.PageOffset).Select(&resources)
+	}); err != nil {
+		return nil, err
+	}
+	normalizeSlowQueries(resources)
+	return resources, nil
+}
+
+// SlowQueryGroups groups every slow query matching the filter by fingerprint and pages over the groups.
+func (p PGRepository) SlowQueryGroups(ctx context.Context, req model.SlowQueriesRequest) ([]*model.SlowQueryGroup, error) {
+	var resources []*model.SlowQueryRecord
+	if err := p.withTimeout(ctx, func(db orm.DB) error {
+		query, err := slowQueryQuery(ctx, db, req)
+		if err != nil {
+			return err
+		}
+		return query.Select(&resources)
+	}); err != nil {
+		return nil, err
 	}
-	query.Order(req.OrderBy)
-	if req.PageSize > defaultLimit {
-		req.PageSize = defaultLimit
+	normalizeSlowQueries(resources)
+
+	groups := model.GroupSlowQueries(resources)
+	if req.PageSize > p.maxPageSize {
+		req.PageSize = p.maxPageSize
 	}
 	if req.PageOffset < 0 {
 		req.PageOffset = 0
 	}
-	if err := query.Limit(req.PageSize).Offset(req.PageOffset).Select(&resources); err != nil {
-		return nil, err
+	if req.PageOffset >= len(groups) {
+		return nil, nil
+	}
+	groups = groups[req.PageOffset:]
+	if req.PageSize > 0 && req.PageSize < len(groups) {
+		groups = groups[:req.PageSize]
+	}
+	return groups, nil
+}
+
+func slowQueryQuery(ctx context.Context, db orm.DB, req model.SlowQueriesRequest) (*orm.Query, error) {
+	query := db.ModelContext(ctx, &model.SlowQueryRecord{}).TableExpr("(?)", pg.Safe(slowQuerySQL))
+	if err := listing.ApplyFilters(req.Filter, filters.SlowQueries.Config, query); err != nil {
+		return nil, fmt.Errorf("[slowQuery] error in filter: %v", err)
+	}
+	if req.ExcludeSelf {
+		query.Where("pid <> pg_backend_pid()")
+	}
+	return query, nil
+}
+
+func normalizeSlowQueries(resources []*model.SlowQueryRecord) {
+	for _, r := range resources {
+		r.NormalizedQuery = querynorm.Normalize(r.Query)
+		r.Fingerprint = querynorm.Fingerprint(r.Query)
 	}
-	return resources, nil
 }
 
 func (p PGRepository) Create(ctx context.Context, resource *model.Entry) (*model.Entry, error) {
-	if _, err := p.db.Model(resource).Insert(); err != nil {
+	if err := p.runInTransaction(ctx, func(tx *pg.Tx) error {
+		_, err := tx.ModelContext(ctx, resource).Insert()
+		return err
+	}); err != nil {
 		return nil, err
 	}
 	return resource, nil
@@ -95,18 +286,14 @@ func (p PGRepository) Create(ctx context.Context, resource *model.Entry) (*model
 
 func (p PGRepository) ListEntries(ctx context.Context, req model.ListEntriesRequest) ([]*model.Entry, error) {
 	var resources []*model.Entry
-	query := p.db.ModelContext(ctx, &model.Entry{})
-	if err := listing.ApplyFilters(req.Filter, entryConfig, query); err != nil {
-		return nil, fmt.Errorf("error in filter: %v", err)
-	}
-	query.Order(req.OrderBy)
-	if req.PageSize > defaultLimit {
-		req.PageSize = defaultLimit
-	}
-	if req.PageOffset < 0 {
-		req.PageOffset = 0
-	}
-	if err := query.Limit(req.PageSize).Offset(req.PageOffset).Select(&resources); err != nil {
+	if err := p.read(ctx, func(db orm.DB) error {
+		resources = nil
+		query := db.ModelContext(ctx, &model.Entry{})
+		if err := listing.ApplyFilters(req.Filter, filters.Entries.Config, query); err != nil {
+			return fmt.Errorf("error in filter: %v", err)
+		}
+		return p.paginate(query, req.OrderBy, req.PageSize, req.PageOffset).Select(&resources)
+	}); err != nil {
 		return nil, err
 	}
 	return resources, nil
@@ -114,12 +301,14 @@ func (p PGRepository) ListEntries(ctx context.Context, req model.ListEntriesRequ
 
 func (p PGRepository) GetByID(ctx context.Context, id uuid.UUID, showDeleted bool, queryHook dataprovider.QueryHook) (*model.Entry, error) {
 	resource := &model.Entry{ID: id}
-	query := p.db.ModelContext(ctx, resource)
-	if showDeleted {
-		query.AllWithDeleted()
-	}
-	queryHook(query)
-	if err := query.Select(); err != nil {
+	if err := p.read(ctx, func(db orm.DB) error {
+		query := db.ModelContext(ctx, resource)
+		if showDeleted {
+			query.AllWithDeleted()
+		}
+		queryHook(query)
+		return query.Select()
+	}); err != nil {
 		if err == pg.ErrNoRows {
 			return nil, nil
 		}
@@ -130,8 +319,8 @@ func (p PGRepository) GetByID(ctx context.Context, id uuid.UUID, showDeleted boo
 }
 
 func (p PGRepository) Update(ctx context.Context, resource *model.Entry, fields []string, queryHook dataprovider.QueryHook) (*model.Entry, error) {
-	if err := p.db.WithContext(ctx).RunInTransaction(ctx, func(tx *pg.Tx) error {
-		query := tx.Model(resource).Returning("*").Column("update_time")
+	if err := p.runInTransaction(ctx, func(tx *pg.Tx) error {
+		query := tx.ModelContext(ctx, resource).Returning("*").Column("update_time")
 		for _, col := range fields {
 			query.Column(col)
 		}
@@ -152,8 +341,8 @@ func (p PGRepository) Update(ctx context.Context, resource *model.Entry, fields
 }
 
 func (p PGRepository) Delete(ctx context.Context, resource *model.Entry, queryHook dataprovider.QueryHook) (*model.Entry, error) {
-	if err := p.db.WithContext(ctx).RunInTransaction(ctx, func(tx *pg.Tx) error {
-		query := tx.Model(resource).WherePK().Returning("*")
+	if err := p.runInTransaction(ctx, func(tx *pg.Tx) error {
+		query := tx.ModelContext(ctx, resource).WherePK().Returning("*")
 		if queryHook != nil {
 			queryHook(query)
 		}
@@ -1,6 +1,7 @@
 package postgres_test
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,6 +11,8 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider/postgres"
+	"github.com/rahul2393/city-falcon-assignment/pkg/migrate"
 	testutil "github.com/rahul2393/city-falcon-assignment/tests/dbutils"
 )
 
@@ -51,37 +54,45 @@ func TestPGDataProvider_SlowQuery(t *testing.T) {
 		{
 			name: "valid input",
 			args: model.SlowQueriesRequest{
-				PageSize:   1001,
-				PageOffset: 0,
-				OrderBy:    "pid",
-				Filter:     "",
+				ListParams: model.ListParams{
+					PageSize:   1001,
+					PageOffset: 0,
+					OrderBy:    "pid",
+					Filter:     "",
+				},
 			},
 		},
 		{
 			name: "success with invalid pageOffset",
 			args: model.SlowQueriesRequest{
-				PageSize:   1000000000,
-				PageOffset: -1,
-				OrderBy:    "pid",
-				Filter:     "",
+				ListParams: model.ListParams{
+					PageSize:   1000000000,
+					PageOffset: -1,
+					OrderBy:    "pid",
+					Filter:     "",
+				},
 			},
 		},
 		{
 			name: "success with valid filter",
 			args: model.SlowQueriesRequest{
-				PageSize:   100,
-				PageOffset: 0,
-				OrderBy:    "pid",
-				Filter:     `database_name!=""`,
+				ListParams: model.ListParams{
+					PageSize:   100,
+					PageOffset: 0,
+					OrderBy:    "pid",
+					Filter:     `database_name!=""`,
+				},
 			},
 		},
 		{
 			name: "invalid filter",
 			args: model.SlowQueriesRequest{
-				PageSize:   100,
-				PageOffset: 0,
-				OrderBy:    "pid",
-				Filter:     "ad!=><",
+				ListParams: model.ListParams{
+					PageSize:   100,
+					PageOffset: 0,
+					OrderBy:    "pid",
+					Filter:     "ad!=><",
+				},
 			},
 			wantErr: "[slowQuery] error in filter: parse: 1:5: unexpected token \">\" (expected <int> | <float> | <string> | \"TRUE\" | \"FALSE\")",
 		},
@@ -95,6 +106,287 @@ func TestPGDataProvider_SlowQuery(t *testing.T) {
 			}
 		})
 	}
+
+	// the query reading pg_stat_activity is active while it runs
+	self := model.SlowQueriesRequest{ListParams: model.ListParams{PageSize: 100, Filter: `state = "active" AND query:"pg_stat_activity"`}}
+	if records, err := p.SlowQuery(ctx, self); err != nil || len(records) == 0 {
+		t.Errorf("Persist.SlowQuery() = %v, %v, want its own backend", records, err)
+	}
+	self.ExcludeSelf = true
+	if records, err := p.SlowQuery(ctx, self); err != nil || len(records) != 0 {
+		t.Errorf("Persist.SlowQuery() excluding its own backend = %v, %v, want none", records, err)
+	}
+}
+
+func TestPGDataProvider_SlowQueryGroups(t *testing.T) {
+	ctx := util.Context
+	p := util.Persist
+
+	groups, err := p.SlowQueryGroups(ctx, model.SlowQueriesRequest{
+		ListParams: model.ListParams{
+			PageSize: 100,
+			Filter:   `duration_ms >= 0`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Persist.SlowQueryGroups() error = %v", err)
+	}
+	for _, g := range groups {
+		if g.Fingerprint == "" || g.Count != len(g.PIDs) {
+			t.Errorf("Persist.SlowQueryGroups() invalid group %+v", g)
+		}
+	}
+}
+
+func TestPGDataProvider_BlockingQueries(t *testing.T) {
+	ctx := util.Context
+	p := util.Persist
+
+	tests := []struct {
+		name    string
+		args    model.BlockingQueriesRequest
+		wantErr string
+	}{
+		{
+			name: "valid input",
+			args: model.BlockingQueriesRequest{
+				ListParams: model.ListParams{
+					PageSize:   1001,
+					PageOffset: -1,
+					OrderBy:    "blocked_pid",
+				},
+			},
+		},
+		{
+			name: "success with valid filter",
+			args: model.BlockingQueriesRequest{
+				ListParams: model.ListParams{
+					PageSize: 100,
+					OrderBy:  "blocking_pid",
+					Filter:   `lock_mode: "Exclusive" blocked_duration_ms > 10`,
+				},
+			},
+		},
+		{
+			name: "unknown field in filter",
+			args: model.BlockingQueriesRequest{
+				ListParams: model.ListParams{
+					PageSize: 100,
+					OrderBy:  "blocked_pid",
+					Filter:   `datname = "postgres"`,
+				},
+			},
+			wantErr: `[blockingQueries] error in filter: field: "datname": not found in model`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.BlockingQueries(ctx, tt.args)
+			if (err != nil || tt.wantErr != "") && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("name: %v, Persist.BlockingQueries() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPGDataProvider_SlowQueryHistory(t *testing.T) {
+	ctx := util.Context
+	p := util.Persist
+
+	if _, err := p.SampleSlowQueries(ctx, 0); err != nil {
+		t.Fatalf("Persist.SampleSlowQueries() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    model.SlowQueryHistoryRequest
+		wantErr string
+	}{
+		{
+			name: "valid input",
+			args: model.SlowQueryHistoryRequest{
+				ListParams: model.ListParams{
+					PageSize:   1001,
+					PageOffset: -1,
+					OrderBy:    "last_seen DESC",
+				},
+			},
+		},
+		{
+			name: "success with filter and time range",
+			args: model.SlowQueryHistoryRequest{
+				ListParams: model.ListParams{
+					PageSize: 100,
+					OrderBy:  "max_duration_ms DESC",
+					Filter:   `max_duration_ms >= 0 database_name != ""`,
+				},
+				From: time.Now().Add(-time.Hour),
+				To:   time.Now(),
+			},
+		},
+		{
+			name: "invalid filter",
+			args: model.SlowQueryHistoryRequest{
+				ListParams: model.ListParams{
+					PageSize: 100,
+					OrderBy:  "pid",
+					Filter:   "ad!=><",
+				},
+			},
+			wantErr: "[slowQueryHistory] error in filter: parse: 1:5: unexpected token \">\" (expected <int> | <float> | <string> | \"TRUE\" | \"FALSE\")",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.SlowQueryHistory(ctx, tt.args)
+			if (err != nil || tt.wantErr != "") && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("name: %v, Persist.SlowQueryHistory() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPGDataProvider_PruneSlowQueryHistory(t *testing.T) {
+	ctx := util.Context
+	p := util.Persist
+
+	if _, err := p.SampleSlowQueries(ctx, 0); err != nil {
+		t.Fatalf("Persist.SampleSlowQueries() error = %v", err)
+	}
+	if _, err := p.PruneSlowQueryHistory(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Persist.PruneSlowQueryHistory() error = %v", err)
+	}
+	records, err := p.SlowQueryHistory(ctx, model.SlowQueryHistoryRequest{ListParams: model.ListParams{PageSize: 100, OrderBy: "last_seen"}})
+	if err != nil {
+		t.Fatalf("Persist.SlowQueryHistory() error = %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Persist.PruneSlowQueryHistory() kept %d records last seen before the cutoff", len(records))
+	}
+}
+
+func TestPGDataProvider_Replication(t *testing.T) {
+	ctx := util.Context
+	p := util.Persist
+
+	req := model.ReplicationRequest{ListParams: model.ListParams{PageSize: 1001, PageOffset: -1}}
+	invalid := model.ReplicationRequest{ListParams: model.ListParams{PageSize: 100, Filter: "ad!=><"}}
+
+	req.OrderBy = "application_name"
+	if _, err := p.Replication(ctx, req); err != nil {
+		t.Errorf("Persist.Replication() error = %v", err)
+	}
+	if _, err := p.Replication(ctx, invalid); err == nil {
+		t.Errorf("Persist.Replication() with invalid filter error = nil")
+	}
+
+	req.OrderBy = "pid"
+	if _, err := p.WALReceivers(ctx, req); err != nil {
+		t.Errorf("Persist.WALReceivers() error = %v", err)
+	}
+
+	req.OrderBy = "retained_bytes DESC"
+	req.Filter = `active = false`
+	if _, err := p.ReplicationSlots(ctx, req); err != nil {
+		t.Errorf("Persist.ReplicationSlots() error = %v", err)
+	}
+}
+
+func TestPGDataProvider_TableHealth(t *testing.T) {
+	ctx := util.Context
+	p := util.Persist
+
+	req := model.TableHealthRequest{ListParams: model.ListParams{PageSize: 100, OrderBy: "total_bytes DESC", Filter: `table_name = "entries"`}}
+	tables, err := p.TableHealth(ctx, req)
+	if err != nil {
+		t.Fatalf("Persist.TableHealth() error = %v", err)
+	}
+	if len(tables) != 1 || tables[0].TotalBytes == 0 {
+		t.Errorf("Persist.TableHealth() = %v, want the entries table", tables)
+	}
+
+	indexes, err := p.IndexHealth(ctx, req)
+	if err != nil {
+		t.Fatalf("Persist.IndexHealth() error = %v", err)
+	}
+	if len(indexes) == 0 || !indexes[0].IsPrimary || indexes[0].Unused || indexes[0].Duplicate {
+		t.Errorf("Persist.IndexHealth() = %v, want the primary key of entries", indexes)
+	}
+
+	if _, err := p.IndexHealth(ctx, model.TableHealthRequest{ListParams: model.ListParams{PageSize: 100, Filter: "ad!=><"}}); err == nil {
+		t.Errorf("Persist.IndexHealth() with invalid filter error = nil")
+	}
+}
+
+func TestPGDataProvider_BackendStates(t *testing.T) {
+	states, err := util.Persist.BackendStates(util.Context)
+	if err != nil {
+		t.Fatalf("Persist.BackendStates() error = %v", err)
+	}
+	var backends int64
+	for _, s := range states {
+		backends += s.Backends
+	}
+	if backends == 0 {
+		t.Errorf("Persist.BackendStates() = %v, want at least the test connection", states)
+	}
+}
+
+func TestPGDataProvider_CheckSchema(t *testing.T) {
+	ctx := util.Context
+	if err := util.SetupDB(); err != nil {
+		util.Log.Panicf("util.SetupDB(): %v", err)
+	}
+	defer cleanEntryData()
+	migrator, err := postgres.NewMigrator(util.DBURL)
+	if err != nil {
+		t.Fatalf("postgres.NewMigrator() error = %v", err)
+	}
+	defer migrator.Close()
+
+	if err := util.Persist.CheckSchema(ctx); err != nil {
+		t.Errorf("Persist.CheckSchema() error = %v", err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("migrator.Up() = %v, %v, want nothing left to apply", applied, err)
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 {
+		t.Fatalf("migrator.Down(1) = %v, %v, want the last migration reverted", reverted, err)
+	}
+	if err := util.Persist.CheckSchema(ctx); err == nil {
+		t.Errorf("Persist.CheckSchema() with a pending migration error = nil")
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("migrator.Status() error = %v", err)
+	}
+	if last := statuses[len(statuses)-1]; last.Version != reverted[0].Version || last.AppliedAt != nil {
+		t.Errorf("migrator.Status() last = %+v, want %d pending", last, reverted[0].Version)
+	}
+
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 1 {
+		t.Fatalf("migrator.Up() = %v, %v, want the reverted migration applied again", applied, err)
+	}
+	if err := util.Persist.CheckSchema(ctx); err != nil {
+		t.Errorf("Persist.CheckSchema() error = %v", err)
+	}
+
+	// the baseline adopts entries, which migrate down must never drop
+	if _, err := util.Persist.Create(ctx, &model.Entry{ID: uuid.New(), Version: 1}); err != nil {
+		t.Fatalf("Persist.Create() error = %v", err)
+	}
+	reverted, err = migrator.Down(ctx, len(statuses))
+	if !errors.Is(err, migrate.ErrIrreversible) || len(reverted) != len(statuses)-1 {
+		t.Errorf("migrator.Down(all) = %v, %v, want every migration but the baseline reverted", reverted, err)
+	}
+	if entries, err := util.Persist.ListEntries(ctx, model.ListEntriesRequest{ListParams: model.ListParams{PageSize: 10}}); err != nil || len(entries) != 1 {
+		t.Errorf("Persist.ListEntries() after migrate down = %v, %v, want the entry kept", entries, err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrator.Up() error = %v", err)
+	}
 }
 
 func TestPGDataProvider_ListEntries(t *testing.T) {
@@ -115,37 +407,45 @@ func TestPGDataProvider_ListEntries(t *testing.T) {
 		{
 			name: "valid input",
 			args: model.ListEntriesRequest{
-				PageSize:   100,
-				PageOffset: 0,
-				OrderBy:    "version",
-				Filter:     "",
+				ListParams: model.ListParams{
+					PageSize:   100,
+					PageOffset: 0,
+					OrderBy:    "version",
+					Filter:     "",
+				},
 			},
 		},
 		{
 			name: "success with invalid pageOffset",
 			args: model.ListEntriesRequest{
-				PageSize:   1000000000,
-				PageOffset: -1,
-				OrderBy:    "version",
-				Filter:     "",
+				ListParams: model.ListParams{
+					PageSize:   1000000000,
+					PageOffset: -1,
+					OrderBy:    "version",
+					Filter:     "",
+				},
 			},
 		},
 		{
 			name: "success with valid filter",
 			args: model.ListEntriesRequest{
-				PageSize:   100,
-				PageOffset: 0,
-				OrderBy:    "version",
-				Filter:     `version!="4"`,
+				ListParams: model.ListParams{
+					PageSize:   100,
+					PageOffset: 0,
+					OrderBy:    "version",
+					Filter:     `version!="4"`,
+				},
 			},
 		},
 		{
 			name: "invalid filter",
 			args: model.ListEntriesRequest{
-				PageSize:   100,
-				PageOffset: 0,
-				OrderBy:    "version",
-				Filter:     "ad!=><",
+				ListParams: model.ListParams{
+					PageSize:   100,
+					PageOffset: 0,
+					OrderBy:    "version",
+					Filter:     "ad!=><",
+				},
 			},
 			wantErr: "error in filter: parse: 1:5: unexpected token \">\" (expected <int> | <float> | <string> | \"TRUE\" | \"FALSE\")",
 		},
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+
+	pg "github.com/go-pg/pg/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/tracing"
+	"github.com/rahul2393/city-falcon-assignment/pkg/redact"
+)
+
+// queryTracer records every query as a child span of the span found in its context.
+type queryTracer struct {
+	database string
+	redactor *redact.Redactor
+}
+
+func (t queryTracer) BeforeQuery(ctx context.Context, q *pg.QueryEvent) (context.Context, error) {
+	ctx, _ = tracing.Tracer().Start(ctx, queryOperation(q),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBName(t.database)))
+	return ctx, nil
+}
+
+func (t queryTracer) AfterQuery(ctx context.Context, q *pg.QueryEvent) error {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	if !span.IsRecording() {
+		return nil
+	}
+	if query, err := q.FormattedQuery(); err == nil {
+		span.SetAttributes(semconv.DBStatement(t.redactor.Redact(string(query))))
+	}
+	if q.Result != nil {
+		span.SetAttributes(attribute.Int("db.rows_affected", q.Result.RowsAffected()))
+	}
+	if q.Err != nil && q.Err != pg.ErrNoRows {
+		span.RecordError(q.Err)
+		span.SetStatus(codes.Error, q.Err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	pg "github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+const (
+	defaultMinRetryBackoff = 250 * time.Millisecond
+	defaultMaxRetryBackoff = 4 * time.Second
+)
+
+// retryPolicy bounds the retries of the transactions failing with a transient error.
+type retryPolicy struct {
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// backoff returns the wait before the retry following attempt, doubling from minBackoff up to maxBackoff with
+// jitter so that transactions conflicting with each other do not retry in lockstep.
+func (r retryPolicy) backoff(attempt int) time.Duration {
+	d := r.minBackoff << attempt
+	if d <= 0 || d > r.maxBackoff {
+		d = r.maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryable reports whether a transaction failing with err may be run again without applying its writes twice: on
+// serialization failures and deadlocks, which roll it back, and on connections that could not be dialed, which never
+// sent it. Lost connections and timeouts are not retried, they may have cut the reply to a COMMIT that succeeded.
+func retryable(err error) bool {
+	var pgErr pg.Error
+	if errors.As(err, &pgErr) {
+		code := pgErr.Field('C')
+		return code == "40001" || code == "40P01"
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryableRead reports whether a read failing with err may succeed when run again: on the errors of retryable and
+// on lost connections and timeouts as well.
+func retryableRead(err error) bool {
+	if retryable(err) {
+		return true
+	}
+	var pgErr pg.Error
+	if errors.As(err, &pgErr) {
+		code := pgErr.Field('C')
+		// 08: connection exception, 57P01: admin_shutdown
+		return strings.HasPrefix(code, "08") || code == "57P01"
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retrying runs fn again after a backoff when it fails with an error retryable reports, up to the retries of the
+// repository. It is the only layer of retries: the pool itself does not retry.
+func (p PGRepository) retrying(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.retry.maxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		timer := time.NewTimer(p.retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// runInTransaction runs fn in a transaction whose statement_timeout is the time left before the deadline of ctx, if
+// any. The transaction is retried on the errors of retryable, so fn must not have effects outside of tx.
+func (p PGRepository) runInTransaction(ctx context.Context, fn func(tx *pg.Tx) error) error {
+	return p.retrying(ctx, retryable, func() error {
+		return p.db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+			if err := setStatementTimeout(ctx, tx); err != nil {
+				return err
+			}
+			return fn(tx)
+		})
+	})
+}
+
+// withTimeout runs the reads of fn on the pool, bounded by the deadline of ctx: once it passes the pool sends the
+// server a cancel request for the query in progress. Reads are retried on the errors of retryableRead, so fn must
+// reset whatever it selects into and must not write: writes go through runInTransaction.
+func (p PGRepository) withTimeout(ctx context.Context, fn func(db orm.DB) error) error {
+	return p.retrying(ctx, retryableRead, func() error {
+		return fn(p.db.WithContext(ctx))
+	})
+}
+
+// setStatementTimeout sets the statement_timeout of tx to the time left before the deadline of ctx.
+func setStatementTimeout(ctx context.Context, tx *pg.Tx) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	// statement_timeout = 0 would disable the timeout
+	ms := time.Until(deadline).Milliseconds()
+	if ms <= 0 {
+		return context.DeadlineExceeded
+	}
+	_, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = ?", ms)
+	return err
+}
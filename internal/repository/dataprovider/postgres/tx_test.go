@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// pgError stands for the errors returned by the server.
+type pgError string
+
+func (e pgError) Error() string            { return "ERROR #" + string(e) }
+func (e pgError) Field(field byte) string  { return string(e) }
+func (e pgError) IntegrityViolation() bool { return false }
+
+// timeoutError stands for the read timeouts of the connections.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		want     bool
+		wantRead bool
+	}{
+		{pgError("40001"), true, true},
+		{fmt.Errorf("update: %w", pgError("40P01")), true, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true, true},
+		{pgError("08006"), false, true},
+		{pgError("57P01"), false, true},
+		{io.EOF, false, true},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, false, true},
+		// the reply to a COMMIT that succeeded may be the one timing out
+		{&net.OpError{Op: "read", Err: timeoutError{}}, false, true},
+		{pgError("23505"), false, false},
+		{pgError("57014"), false, false},
+		{errors.New("error in filter"), false, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+		if got := retryableRead(tt.err); got != tt.wantRead {
+			t.Errorf("retryableRead(%v) = %v, want %v", tt.err, got, tt.wantRead)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	r := retryPolicy{maxRetries: 5, minBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+	for attempt, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for i := 0; i < 20; i++ {
+			if got := r.backoff(attempt); got < max/2 || got > max {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, got, max/2, max)
+			}
+		}
+	}
+	if got := r.backoff(70); got < r.maxBackoff/2 || got > r.maxBackoff {
+		t.Errorf("backoff(70) = %v, want it capped at %v", got, r.maxBackoff)
+	}
+}
+
+func TestRetrying(t *testing.T) {
+	p := PGRepository{retry: retryPolicy{maxRetries: 2, minBackoff: time.Millisecond, maxBackoff: time.Millisecond}}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", []error{nil}, 1, false},
+		{"transient failure", []error{pgError("40001"), io.EOF, nil}, 3, false},
+		{"retries exhausted", []error{pgError("40001"), pgError("40001"), pgError("40001"), nil}, 3, true},
+		{"permanent failure", []error{pgError("23505"), nil}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := p.retrying(context.Background(), retryableRead, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if calls != tt.wantCalls || (err != nil) != tt.wantErr {
+				t.Errorf("retrying() called fn %d times and returned %v, want %d calls, error %v", calls, err, tt.wantCalls, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package requestlog assigns request IDs and request-scoped loggers, and logs an access line per request.
+package requestlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/fiberutil"
+)
+
+// Header carries the request ID, taken from the request when valid and echoed in the response.
+const Header = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	loggerKey
+)
+
+// Middleware stores the request ID and a logger carrying it in the user context of every request, then logs the
+// request once it is handled.
+func Middleware(logger *logrus.Entry) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		id := c.Get(Header)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(Header, id)
+
+		log := logger.WithField("request_id", id)
+		ctx := context.WithValue(c.UserContext(), requestIDKey, id)
+		c.SetUserContext(context.WithValue(ctx, loggerKey, log))
+
+		err := c.Next()
+
+		// picks up the fields added by the next handlers, e.g. the principal
+		log = Logger(c.UserContext(), log).WithFields(logrus.Fields{
+			"method":     c.Method(),
+			"route":      c.Route().Path,
+			"path":       c.Path(),
+			"status":     fiberutil.StatusCode(c, err),
+			"latency_ms": time.Since(start).Milliseconds(),
+			"bytes":      len(c.Response().Body()),
+		})
+		if err != nil {
+			log = log.WithError(err)
+		}
+		log.Info("request handled")
+		return err
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII characters without spaces, so that they are safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID returns the ID of the request ctx belongs to, or an empty string outside of requests.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Logger returns the logger of the request ctx belongs to, or fallback outside of requests.
+func Logger(ctx context.Context, fallback *logrus.Entry) *logrus.Entry {
+	if log, ok := ctx.Value(loggerKey).(*logrus.Entry); ok {
+		return log
+	}
+	return fallback
+}
+
+// WithFields returns a copy of ctx whose request logger, and access log line, carry fields too.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	log, ok := ctx.Value(loggerKey).(*logrus.Entry)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerKey, log.WithFields(fields))
+}
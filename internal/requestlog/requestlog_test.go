@@ -0,0 +1,89 @@
+package requestlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestMiddleware(t *testing.T) {
+	base, hook := test.NewNullLogger()
+	app := fiber.New()
+	app.Use(Middleware(base.WithField("app", "test")))
+
+	var gotID string
+	app.Get("/entry/:id", func(c *fiber.Ctx) error {
+		gotID = RequestID(c.UserContext())
+		Logger(c.UserContext(), nil).Info("handling")
+		return c.SendString("hello")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.NewError(http.StatusNotFound, "nope")
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		wantID   string
+		status   int
+		messages int
+	}{
+		{"propagated", "/entry/1", "abc-123", "abc-123", 200, 2},
+		{"generated", "/entry/1", "", "", 200, 2},
+		{"invalid replaced", "/entry/1", "bad id\n", "", 200, 2},
+		{"error", "/fail", "err-1", "err-1", 404, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			gotID = ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := resp.Header.Get(Header)
+			if tt.wantID != "" && id != tt.wantID {
+				t.Errorf("response %s = %q, want %q", Header, id, tt.wantID)
+			}
+			if !validRequestID(id) {
+				t.Errorf("response %s = %q, want a valid ID", Header, id)
+			}
+			if gotID != "" && gotID != id {
+				t.Errorf("handler saw request ID %q, response has %q", gotID, id)
+			}
+
+			entries := hook.AllEntries()
+			if len(entries) != tt.messages {
+				t.Fatalf("got %d log entries, want %d", len(entries), tt.messages)
+			}
+			for _, e := range entries {
+				if e.Data["request_id"] != id || e.Data["app"] != "test" {
+					t.Errorf("log entry %q fields = %v, want request_id %q", e.Message, e.Data, id)
+				}
+			}
+			access := hook.LastEntry()
+			if access.Data["status"] != tt.status || access.Data["method"] != "GET" || access.Data["path"] != tt.path {
+				t.Errorf("access log fields = %v", access.Data)
+			}
+		})
+	}
+}
+
+func TestLoggerFallback(t *testing.T) {
+	fallback := logrus.New().WithField("component", "x")
+	if got := Logger(httptest.NewRequest(http.MethodGet, "/", nil).Context(), fallback); got != fallback {
+		t.Errorf("Logger() outside of a request = %v, want the fallback", got)
+	}
+	if got := RequestID(httptest.NewRequest(http.MethodGet, "/", nil).Context()); got != "" {
+		t.Errorf("RequestID() outside of a request = %q, want empty", got)
+	}
+}
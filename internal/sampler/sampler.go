@@ -0,0 +1,93 @@
+// Package sampler periodically records long-running queries into the slow query history.
+package sampler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+)
+
+// Sampler snapshots pg_stat_activity through a Provider every Interval and persists queries running longer
+// than Threshold, then deletes the queries last seen longer than Retention ago.
+type Sampler struct {
+	provider  dataprovider.Provider
+	interval  time.Duration
+	threshold atomic.Int64
+	retention atomic.Int64
+	logger    *logrus.Entry
+	now       func() time.Time
+}
+
+// New creates a Sampler. Run must be called to start sampling.
+func New(provider dataprovider.Provider, interval, threshold, retention time.Duration, logger *logrus.Entry) *Sampler {
+	s := &Sampler{
+		provider: provider,
+		interval: interval,
+		logger:   logger.WithField("component", "sampler"),
+		now:      time.Now,
+	}
+	s.SetThreshold(threshold)
+	s.SetRetention(retention)
+	return s
+}
+
+// SetThreshold changes the threshold from the next sample on.
+func (s *Sampler) SetThreshold(threshold time.Duration) {
+	s.threshold.Store(int64(threshold))
+}
+
+// SetRetention changes the retention of the history from the next sample on, 0 keeping it forever.
+func (s *Sampler) SetRetention(retention time.Duration) {
+	s.retention.Store(int64(retention))
+}
+
+// Run samples until ctx is cancelled. A failed sample is logged and retried on the next tick.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sample(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sampler) sample(ctx context.Context) {
+	threshold := time.Duration(s.threshold.Load())
+	n, err := s.provider.SampleSlowQueries(ctx, threshold)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Errorf("failed to sample slow queries: %v", err)
+		}
+		return
+	}
+	if n > 0 {
+		s.logger.Debugf("recorded %d slow queries above %s", n, threshold)
+	}
+	s.prune(ctx)
+}
+
+func (s *Sampler) prune(ctx context.Context) {
+	retention := time.Duration(s.retention.Load())
+	if retention <= 0 {
+		return
+	}
+	n, err := s.provider.PruneSlowQueryHistory(ctx, s.now().Add(-retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Errorf("failed to prune the slow query history: %v", err)
+		}
+		return
+	}
+	if n > 0 {
+		s.logger.Debugf("deleted %d slow queries last seen more than %s ago", n, retention)
+	}
+}
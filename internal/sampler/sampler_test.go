@@ -0,0 +1,140 @@
+package sampler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+)
+
+type fakeProvider struct {
+	dataprovider.Provider
+
+	mu         sync.Mutex
+	thresholds []time.Duration
+	err        error
+	prunes     []time.Time
+	pruneErr   error
+}
+
+func (f *fakeProvider) SampleSlowQueries(ctx context.Context, threshold time.Duration) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.thresholds = append(f.thresholds, threshold)
+	return 1, f.err
+}
+
+func (f *fakeProvider) PruneSlowQueryHistory(ctx context.Context, before time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prunes = append(f.prunes, before)
+	return 1, f.pruneErr
+}
+
+func (f *fakeProvider) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.thresholds)
+}
+
+func TestSampler_Run(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"success", nil},
+		{"errors are retried", errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProvider{err: tt.err}
+			s := New(p, 5*time.Millisecond, time.Second, 0, logrus.New().WithField("test", true))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				s.Run(ctx)
+				close(done)
+			}()
+
+			deadline := time.Now().Add(time.Second)
+			for p.calls() < 3 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			<-done
+
+			if p.calls() < 3 {
+				t.Fatalf("got %d samples, want at least 3", p.calls())
+			}
+			for _, th := range p.thresholds {
+				if th != time.Second {
+					t.Errorf("threshold = %v, want %v", th, time.Second)
+				}
+			}
+		})
+	}
+}
+
+func TestSampler_SetThreshold(t *testing.T) {
+	p := &fakeProvider{}
+	s := New(p, time.Hour, time.Second, 0, logrus.New().WithField("test", true))
+	s.sample(context.Background())
+	s.SetThreshold(5 * time.Second)
+	s.sample(context.Background())
+
+	want := []time.Duration{time.Second, 5 * time.Second}
+	if len(p.thresholds) != len(want) || p.thresholds[0] != want[0] || p.thresholds[1] != want[1] {
+		t.Errorf("thresholds = %v, want %v", p.thresholds, want)
+	}
+}
+
+func TestSampler_Prune(t *testing.T) {
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		retention  time.Duration
+		sampleErr  error
+		pruneErr   error
+		wantPrunes []time.Time
+	}{
+		{"keeps the history forever", 0, nil, nil, nil},
+		{"prunes after each sample", time.Hour, nil, nil, []time.Time{now.Add(-time.Hour), now.Add(-time.Hour)}},
+		{"prune errors are retried", time.Hour, nil, errors.New("connection refused"), []time.Time{now.Add(-time.Hour), now.Add(-time.Hour)}},
+		{"skipped when the sample fails", time.Hour, errors.New("connection refused"), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProvider{err: tt.sampleErr, pruneErr: tt.pruneErr}
+			s := New(p, time.Hour, time.Second, tt.retention, logrus.New().WithField("test", true))
+			s.now = func() time.Time { return now }
+			s.sample(context.Background())
+			s.sample(context.Background())
+
+			if !reflect.DeepEqual(p.prunes, tt.wantPrunes) {
+				t.Errorf("prunes = %v, want %v", p.prunes, tt.wantPrunes)
+			}
+		})
+	}
+}
+
+func TestSampler_SetRetention(t *testing.T) {
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	p := &fakeProvider{}
+	s := New(p, time.Hour, time.Second, 0, logrus.New().WithField("test", true))
+	s.now = func() time.Time { return now }
+	s.sample(context.Background())
+	s.SetRetention(24 * time.Hour)
+	s.sample(context.Background())
+
+	want := []time.Time{now.Add(-24 * time.Hour)}
+	if !reflect.DeepEqual(p.prunes, want) {
+		t.Errorf("prunes = %v, want %v", p.prunes, want)
+	}
+}
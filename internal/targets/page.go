@@ -0,0 +1,190 @@
+package targets
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FanOutPage returns the page of pageSize results starting at pageOffset of the results of every target ordered by
+// orderBy, an ORDER BY clause naming the columns of the results by their pg or json tags. fn returns a page of the
+// results of a target ordered by orderBy, at most pageSize of them, all of them when pageSize is 0. With several
+// targets the first pageOffset+pageSize results of each are fetched and merged before the page is cut, results
+// ordered the same keeping the order of their targets. Failures are reported as by FanOut.
+func FanOutPage[T any](ctx context.Context, targets []*Target, orderBy string, pageOffset, pageSize int, fn func(ctx context.Context, t *Target, pageOffset, pageSize int) ([]T, error)) ([]T, map[string]error) {
+	if len(targets) == 1 {
+		return FanOut(ctx, targets, func(ctx context.Context, t *Target) ([]T, error) {
+			return fn(ctx, t, pageOffset, pageSize)
+		})
+	}
+	if pageOffset < 0 {
+		pageOffset = 0
+	}
+	need := 0
+	if pageSize > 0 {
+		need = pageOffset + pageSize
+	}
+	merged, failed := FanOut(ctx, targets, func(ctx context.Context, t *Target) ([]T, error) {
+		return head(ctx, t, need, fn)
+	})
+	sortByOrder(merged, orderBy)
+	if pageOffset >= len(merged) {
+		return nil, failed
+	}
+	merged = merged[pageOffset:]
+	if pageSize > 0 && pageSize < len(merged) {
+		merged = merged[:pageSize]
+	}
+	return merged, failed
+}
+
+// head returns the first n results of t, all of them when n is 0, fetching pages until it has them since fn may
+// return shorter pages than asked for, e.g. capped by the maximum page size.
+func head[T any](ctx context.Context, t *Target, n int, fn func(ctx context.Context, t *Target, pageOffset, pageSize int) ([]T, error)) ([]T, error) {
+	var all []T
+	for n == 0 || len(all) < n {
+		size := 0
+		if n > 0 {
+			size = n - len(all)
+		}
+		page, err := fn(ctx, t, len(all), size)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) == 0 || n == 0 {
+			break
+		}
+	}
+	return all, nil
+}
+
+// orderKey is a column of an ORDER BY clause.
+type orderKey struct {
+	field      []int
+	desc       bool
+	nullsFirst bool
+}
+
+// sortByOrder sorts results, pointers to structs, stably by orderBy. Columns matching no field are ignored. Nil
+// pointers stand for NULL, ordered like Postgres does: last in ascending order and first in descending order, unless
+// the column says NULLS FIRST or NULLS LAST.
+func sortByOrder[T any](results []T, orderBy string) {
+	typ := reflect.TypeOf(results).Elem()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return
+	}
+	var keys []orderKey
+	for _, column := range strings.Split(orderBy, ",") {
+		words := strings.Fields(column)
+		if len(words) == 0 {
+			continue
+		}
+		field, ok := fieldByColumn(typ, strings.Trim(words[0], `"`))
+		if !ok {
+			continue
+		}
+		k := orderKey{field: field, desc: len(words) > 1 && strings.EqualFold(words[1], "DESC")}
+		k.nullsFirst = k.desc
+		if n := len(words); n > 2 && strings.EqualFold(words[n-2], "NULLS") {
+			k.nullsFirst = strings.EqualFold(words[n-1], "FIRST")
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := reflect.Indirect(reflect.ValueOf(results[i])), reflect.Indirect(reflect.ValueOf(results[j]))
+		for _, k := range keys {
+			x, y := a.FieldByIndex(k.field), b.FieldByIndex(k.field)
+			if xNull, yNull := isNull(x), isNull(y); xNull || yNull {
+				if xNull == yNull {
+					continue
+				}
+				return xNull == k.nullsFirst
+			}
+			c := compareValues(reflect.Indirect(x), reflect.Indirect(y))
+			if c == 0 {
+				continue
+			}
+			if k.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// fieldByColumn returns the index of the field of typ named column by its pg tag, or by its json tag when it has
+// none.
+func fieldByColumn(typ reflect.Type, column string) ([]int, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("pg"), ",")
+		if name == "" || name == "-" {
+			name, _, _ = strings.Cut(f.Tag.Get("json"), ",")
+		}
+		if name == column && f.IsExported() {
+			return f.Index, true
+		}
+	}
+	return nil, false
+}
+
+// isNull reports whether v is a nil pointer, which holds a NULL column.
+func isNull(v reflect.Value) bool {
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compareValues compares two values of the same field like Postgres would compare their columns. Strings holding
+// numbers, e.g. the pids of pg_stat_activity, compare as numbers.
+func compareValues(a, b reflect.Value) int {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float(), b.Float())
+	case reflect.Bool:
+		return compareOrdered(boolInt(a.Bool()), boolInt(b.Bool()))
+	case reflect.String:
+		x, errX := strconv.ParseFloat(a.String(), 64)
+		y, errY := strconv.ParseFloat(b.String(), 64)
+		if errX == nil && errY == nil {
+			return compareOrdered(x, y)
+		}
+		return strings.Compare(a.String(), b.String())
+	}
+	return 0
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+// Package targets keeps track of the Postgres servers monitored by the service.
+package targets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+)
+
+// Default is the name of the target monitoring the service's own database.
+const Default = "default"
+
+// All selects every target when used as a target name.
+const All = "*"
+
+// Target is a named monitored server.
+type Target struct {
+	Name    string
+	Monitor dataprovider.Monitor
+}
+
+// Registry holds the configured targets.
+type Registry struct {
+	targets []*Target
+	byName  map[string]*Target
+}
+
+// NewRegistry returns a Registry of targets, ordered by name. Target names must be unique and must not be All.
+func NewRegistry(targets ...*Target) (*Registry, error) {
+	r := &Registry{byName: map[string]*Target{}}
+	for _, t := range targets {
+		if t.Name == "" || t.Name == All {
+			return nil, fmt.Errorf("invalid target name %q", t.Name)
+		}
+		if _, ok := r.byName[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate target %q", t.Name)
+		}
+		r.byName[t.Name] = t
+		r.targets = append(r.targets, t)
+	}
+	sort.Slice(r.targets, func(i, j int) bool { return r.targets[i].Name < r.targets[j].Name })
+	return r, nil
+}
+
+// Get returns the target called name.
+func (r *Registry) Get(name string) (*Target, bool) {
+	t, ok := r.byName[name]
+	return t, ok
+}
+
+// All returns every target ordered by name.
+func (r *Registry) All() []*Target {
+	return r.targets
+}
+
+// Select resolves a target name as given by an API caller: an empty name selects Default, All selects every
+// target.
+func (r *Registry) Select(name string) ([]*Target, error) {
+	switch name {
+	case "":
+		name = Default
+	case All:
+		return r.targets, nil
+	}
+	t, ok := r.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown target %q", name)
+	}
+	return []*Target{t}, nil
+}
+
+// Health pings every target concurrently, each bounded by timeout.
+func (r *Registry) Health(ctx context.Context, timeout time.Duration) []*model.TargetHealth {
+	health := make([]*model.TargetHealth, len(r.targets))
+	var wg sync.WaitGroup
+	for i, t := range r.targets {
+		wg.Add(1)
+		go func(i int, t *Target) {
+			defer wg.Done()
+			health[i] = Check(ctx, t, timeout)
+		}(i, t)
+	}
+	wg.Wait()
+	return health
+}
+
+// Check pings t, bounded by timeout, and reports its health and pool statistics.
+func Check(ctx context.Context, t *Target, timeout time.Duration) *model.TargetHealth {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := t.Monitor.Ping(ctx)
+	h := &model.TargetHealth{
+		Name:      t.Name,
+		Healthy:   err == nil,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Pool:      t.Monitor.PoolStats(),
+	}
+	if err != nil {
+		h.Error = err.Error()
+	}
+	return h
+}
+
+// FanOut calls fn for every target concurrently and concatenates the results in target order. Targets failing
+// with an error are left out of the result and reported in the returned map, keyed by target name.
+func FanOut[T any](ctx context.Context, targets []*Target, fn func(ctx context.Context, t *Target) ([]T, error)) ([]T, map[string]error) {
+	results := make([][]T, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t *Target) {
+			defer wg.Done()
+			results[i], errs[i] = fn(ctx, t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	var merged []T
+	var failed map[string]error
+	for i, t := range targets {
+		if errs[i] != nil {
+			if failed == nil {
+				failed = map[string]error{}
+			}
+			failed[t.Name] = errs[i]
+			continue
+		}
+		merged = append(merged, results[i]...)
+	}
+	return merged, failed
+}
@@ -0,0 +1,237 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+)
+
+type fakeMonitor struct {
+	dataprovider.Monitor
+	pingErr error
+}
+
+func (f fakeMonitor) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f fakeMonitor) PoolStats() model.PoolStats {
+	return model.PoolStats{TotalConns: 1}
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []*Target
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"valid", []*Target{{Name: "b"}, {Name: "a"}}, false},
+		{"empty name", []*Target{{Name: ""}}, true},
+		{"reserved name", []*Target{{Name: All}}, true},
+		{"duplicate", []*Target{{Name: "a"}, {Name: "a"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRegistry(tt.targets...); (err != nil) != tt.wantErr {
+				t.Errorf("NewRegistry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_Select(t *testing.T) {
+	r, err := NewRegistry(&Target{Name: "orders"}, &Target{Name: Default}, &Target{Name: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		want    []string
+		wantErr bool
+	}{
+		{"", []string{Default}, false},
+		{"orders", []string{"orders"}, false},
+		{All, []string{"billing", Default, "orders"}, false},
+		{"missing", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Select(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, t := range got {
+				names = append(names, t.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Select() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Health(t *testing.T) {
+	r, err := NewRegistry(
+		&Target{Name: "up", Monitor: fakeMonitor{}},
+		&Target{Name: "down", Monitor: fakeMonitor{pingErr: errors.New("connection refused")}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	health := r.Health(context.Background(), time.Second)
+	if len(health) != 2 {
+		t.Fatalf("got %d results, want 2", len(health))
+	}
+	if h := health[0]; h.Name != "down" || h.Healthy || h.Error != "connection refused" {
+		t.Errorf("unexpected health %+v", h)
+	}
+	if h := health[1]; h.Name != "up" || !h.Healthy || h.Pool.TotalConns != 1 {
+		t.Errorf("unexpected health %+v", h)
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	list := []*Target{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	got, failed := FanOut(context.Background(), list, func(ctx context.Context, t *Target) ([]string, error) {
+		if t.Name == "b" {
+			return nil, errors.New("boom")
+		}
+		return []string{t.Name + "1", t.Name + "2"}, nil
+	})
+	if want := []string{"a1", "a2", "c1", "c2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FanOut() = %v, want %v", got, want)
+	}
+	if len(failed) != 1 || failed["b"] == nil {
+		t.Errorf("FanOut() failed = %v, want only b", failed)
+	}
+}
+
+// pagedRecord is a result of a target, with a pid held as a string as in pg_stat_activity.
+type pagedRecord struct {
+	Target     string `json:"target"`
+	PID        string `pg:"pid"`
+	DurationMS int64  `pg:"duration_ms,use_zero"`
+}
+
+type vacuumRecord struct {
+	Name       string     `pg:"relname"`
+	LastVacuum *time.Time `pg:"last_vacuum"`
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestFanOutPage(t *testing.T) {
+	rows := map[string][]*pagedRecord{
+		"a": {{"a", "2", 5}, {"a", "9", 1}, {"a", "10", 3}, {"a", "11", 0}},
+		"b": {{"b", "1", 4}, {"b", "3", 7}, {"b", "9", 2}},
+		"c": {{"c", "4", 6}, {"c", "5", 8}, {"c", "12", 9}},
+	}
+	list := []*Target{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	var fetches int
+	var mu sync.Mutex
+	// fetch pages the rows of a target, returning at most 2 of them like a repository with a maximum page size
+	fetch := func(ctx context.Context, t *Target, pageOffset, pageSize int) ([]*pagedRecord, error) {
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+		r := rows[t.Name]
+		if pageOffset > len(r) {
+			pageOffset = len(r)
+		}
+		r = r[pageOffset:]
+		if pageSize == 0 || pageSize > 2 {
+			pageSize = 2
+		}
+		if pageSize < len(r) {
+			r = r[:pageSize]
+		}
+		return r, nil
+	}
+	pids := func(records []*pagedRecord) []string {
+		var got []string
+		for _, r := range records {
+			got = append(got, r.Target+r.PID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name                 string
+		orderBy              string
+		pageOffset, pageSize int
+		want                 []string
+	}{
+		{"first page", "pid", 0, 3, []string{"b1", "a2", "b3"}},
+		{"second page", "pid", 3, 3, []string{"c4", "c5", "a9"}},
+		{"ties keep the target order", "pid ASC", 5, 2, []string{"a9", "b9"}},
+		{"last page", "pid", 8, 3, []string{"a11", "c12"}},
+		{"past the end", "pid", 10, 3, nil},
+		{"descending", "duration_ms DESC", 0, 4, []string{"c12", "c5", "b3", "c4"}},
+		{"json tag", "target desc, pid", 0, 4, []string{"c4", "c5", "c12", "b1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, failed := FanOutPage(context.Background(), list, tt.orderBy, tt.pageOffset, tt.pageSize, fetch)
+			if len(failed) != 0 {
+				t.Fatalf("FanOutPage() failed = %v", failed)
+			}
+			if len(got) > tt.pageSize {
+				t.Errorf("FanOutPage() returned %d results, want at most %d", len(got), tt.pageSize)
+			}
+			if !reflect.DeepEqual(pids(got), tt.want) {
+				t.Errorf("FanOutPage() = %v, want %v", pids(got), tt.want)
+			}
+		})
+	}
+
+	// NULLs, nil pointers, are last in ascending order and first in descending order like in Postgres
+	t0 := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	vacuums := map[string][]*vacuumRecord{
+		"a": {{"a1", &t0}, {"a2", nil}},
+		"b": {{"b1", nil}, {"b2", ptr(t0.Add(-time.Hour))}},
+	}
+	names := func(records []*vacuumRecord) []string {
+		var got []string
+		for _, r := range records {
+			got = append(got, r.Name)
+		}
+		return got
+	}
+	for _, tt := range []struct {
+		orderBy string
+		want    []string
+	}{
+		{"last_vacuum", []string{"b2", "a1", "a2", "b1"}},
+		{"last_vacuum DESC", []string{"a2", "b1", "a1", "b2"}},
+		{"last_vacuum ASC NULLS FIRST", []string{"a2", "b1", "b2", "a1"}},
+		{"last_vacuum DESC NULLS LAST", []string{"a1", "b2", "a2", "b1"}},
+	} {
+		got, _ := FanOutPage(context.Background(), list[:2], tt.orderBy, 0, 4, func(ctx context.Context, t *Target, pageOffset, pageSize int) ([]*vacuumRecord, error) {
+			r := vacuums[t.Name]
+			if pageOffset > len(r) {
+				pageOffset = len(r)
+			}
+			return r[pageOffset:], nil
+		})
+		if !reflect.DeepEqual(names(got), tt.want) {
+			t.Errorf("FanOutPage() ordered by %s = %v, want %v", tt.orderBy, names(got), tt.want)
+		}
+	}
+
+	// a single target is paged by the target itself
+	fetches = 0
+	got, _ := FanOutPage(context.Background(), list[:1], "pid", 1, 2, fetch)
+	if want := []string{"a9", "a10"}; !reflect.DeepEqual(pids(got), want) || fetches != 1 {
+		t.Errorf("FanOutPage() of one target = %v in %d fetches, want %v in 1", pids(got), fetches, want)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package timeout
+
+import "net"
+
+// peerClosed cannot check connections on this platform, where disconnections are only noticed once the response
+// is written.
+func peerClosed(conn net.Conn) (closed, supported bool) {
+	return false, false
+}
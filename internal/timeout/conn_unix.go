@@ -0,0 +1,40 @@
+//go:build unix
+
+package timeout
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerClosed reports whether the peer of conn closed or reset the connection, peeking at its socket without
+// consuming any byte, and whether conn can be checked at all.
+func peerClosed(conn net.Conn) (closed, supported bool) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false, false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false, false
+	}
+	var n int
+	var peekErr error
+	if err := raw.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, peekErr = syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		// never wait for the socket to be readable
+		return true
+	}); err != nil {
+		return true, true
+	}
+	switch {
+	case errors.Is(peekErr, syscall.EAGAIN) || errors.Is(peekErr, syscall.EINTR):
+		return false, true
+	case peekErr != nil:
+		return true, true
+	}
+	// 0 bytes without error is the end of the stream, pending bytes are a pipelined request
+	return n == 0, true
+}
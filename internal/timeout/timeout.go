@@ -0,0 +1,86 @@
+// Package timeout bounds the time spent on each request and cancels the work of the requests whose client
+// disconnected, so that the queries they run are cancelled on the database too.
+package timeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/requestlog"
+)
+
+// StatusClientClosedRequest is the status logged for the requests whose client disconnected before the response,
+// as nginx does.
+const StatusClientClosedRequest = 499
+
+// disconnectPollInterval is how often the connection of a request in progress is checked for a disconnection.
+const disconnectPollInterval = 250 * time.Millisecond
+
+// ErrClientDisconnected is the cause of the cancellation of the context of a request whose client disconnected.
+var ErrClientDisconnected = errors.New("client disconnected")
+
+// Middleware sets a context in the user context of every request which is cancelled once the timeout returned by
+// timeoutFor has elapsed, 0 for none, or when the client disconnects. Requests failing after their timeout has
+// elapsed get 504 with a model.ErrorResponse body, those whose client disconnected are logged with 499.
+func Middleware(timeoutFor func(c *fiber.Ctx) time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancelCause(c.UserContext())
+		defer cancel(nil)
+		timeout := timeoutFor(c)
+		if timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+			defer cancelTimeout()
+		}
+		stop := watchDisconnect(c, cancel)
+		defer stop()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		switch {
+		case errors.Is(context.Cause(ctx), ErrClientDisconnected):
+			c.Status(StatusClientClosedRequest)
+			return nil
+		case errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || c.Response().StatusCode() >= http.StatusInternalServerError):
+			c.Response().ResetBody()
+			return c.Status(http.StatusGatewayTimeout).JSON(model.ErrorResponse{
+				Error:     fmt.Sprintf("request timed out after %s", timeout),
+				RequestID: requestlog.RequestID(ctx),
+			})
+		}
+		return err
+	}
+}
+
+// watchDisconnect cancels the request c with ErrClientDisconnected as soon as its client disconnects, until stop is
+// called. Connections whose state cannot be checked, e.g. TLS ones, are not watched.
+func watchDisconnect(c *fiber.Ctx, cancel context.CancelCauseFunc) (stop func()) {
+	conn := c.Context().Conn()
+	if _, supported := peerClosed(conn); !supported {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if closed, _ := peerClosed(conn); closed {
+					cancel(ErrClientDisconnected)
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,101 @@
+package timeout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+)
+
+func TestMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware(func(c *fiber.Ctx) time.Duration {
+		if c.Path() == "/none" {
+			return 0
+		}
+		return 50 * time.Millisecond
+	}))
+	wait := func(c *fiber.Ctx) error {
+		select {
+		case <-c.UserContext().Done():
+			c.Status(http.StatusInternalServerError)
+			return nil
+		case <-time.After(200 * time.Millisecond):
+			return c.SendString("done")
+		}
+	}
+	app.Get("/slow", wait)
+	app.Get("/none", wait)
+	app.Get("/fast", func(c *fiber.Ctx) error {
+		return c.SendString("done")
+	})
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/slow", http.StatusGatewayTimeout},
+		{"/none", http.StatusOK},
+		{"/fast", http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		if tt.status == http.StatusGatewayTimeout {
+			var body model.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error != "request timed out after 50ms" {
+				t.Errorf("GET %s body = %+v (%v), want the timeout error", tt.path, body, err)
+			}
+		}
+	}
+}
+
+func TestMiddleware_ClientDisconnected(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cause := make(chan error, 1)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(Middleware(func(c *fiber.Ctx) time.Duration { return time.Minute }))
+	app.Get("/wait", func(c *fiber.Ctx) error {
+		select {
+		case <-c.UserContext().Done():
+			cause <- context.Cause(c.UserContext())
+		case <-time.After(5 * time.Second):
+			cause <- nil
+		}
+		return nil
+	})
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("GET /wait HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, supported := peerClosed(conn); !supported {
+		t.Skip("disconnections cannot be detected on this platform")
+	}
+	time.Sleep(100 * time.Millisecond)
+	conn.Close()
+	if err := <-cause; !errors.Is(err, ErrClientDisconnected) {
+		t.Errorf("request cancelled with %v, want ErrClientDisconnected", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package tracing instruments HTTP requests and database queries with OpenTelemetry spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/fiberutil"
+	"github.com/rahul2393/city-falcon-assignment/pkg/listing"
+)
+
+const instrumentationName = "github.com/rahul2393/city-falcon-assignment"
+
+// Tracer returns the tracer of the service from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the W3C trace context propagator and, depending on exporter, a global tracer provider:
+// "otlp" exports spans over OTLP/HTTP as configured by the standard OTEL_EXPORTER_OTLP_* and OTEL_SERVICE_NAME
+// variables, "" or "none" only propagates incoming trace context. The returned function flushes and stops the
+// exporter.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	switch exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("otlptracehttp.New(): %w", err)
+		}
+		tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp))
+		otel.SetTracerProvider(tp)
+		return tp.Shutdown, nil
+	default:
+		return nil, fmt.Errorf("unsupported traces exporter %q", exporter)
+	}
+}
+
+// Middleware starts a server span per request, continuing the trace of the traceparent header, and stores it in
+// the user context of the request. Handlers must pass c.UserContext() on for their queries to join the trace.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		ctx, span := Tracer().Start(ctx, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			// the query string is left out, its filter may compare fields with sensitive values
+			trace.WithAttributes(semconv.HTTPMethod(c.Method()), semconv.HTTPTarget(c.Path())))
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := fiberutil.StatusCode(c, err)
+		if err != nil {
+			span.RecordError(err)
+		}
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+		}
+		return err
+	}
+}
+
+// SetListAttributes records the parameters of a list request on the span of ctx. Only the fields the filter
+// compares are recorded, not the values it compares them with, which may be sensitive.
+func SetListAttributes(ctx context.Context, pageSize, pageOffset int, orderBy, filter string) {
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("list.page_size", pageSize),
+		attribute.Int("list.page_offset", pageOffset),
+		attribute.String("list.order_by", orderBy),
+		attribute.StringSlice("list.filter_fields", filterFields(filter)),
+	)
+}
+
+// filterFields returns the sorted fields compared by filter, none when it does not parse.
+func filterFields(filter string) []string {
+	f, err := listing.Parse(filter)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	fields := []string{}
+	for _, c := range f.Conditions {
+		if !seen[c.Field] {
+			seen[c.Field] = true
+			fields = append(fields, c.Field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// headerCarrier adapts the request headers of a fiber.Ctx to propagation.TextMapCarrier.
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	var keys []string
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func attributes(s tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range s.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/entries/:id", func(c *fiber.Ctx) error {
+		SetListAttributes(c.UserContext(), 10, 20, "create_time", `name = "secret" AND version > 2 AND name != "b"`)
+		_, span := Tracer().Start(c.UserContext(), "select")
+		span.End()
+		return c.SendString("ok")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.NewError(http.StatusServiceUnavailable, "down")
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/entries/1?pageSize=10&filter="+url.QueryEscape(`name = "secret"`), nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	query, server := spans[0], spans[1]
+	if server.Name != "GET /entries/:id" || server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span = %q (%v), want GET /entries/:id", server.Name, server.SpanKind)
+	}
+	if got := server.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("server span trace ID = %s, want the one of traceparent %s", got, traceID)
+	}
+	if server.Parent.SpanID().String() != "00f067aa0ba902b7" || !server.Parent.IsRemote() {
+		t.Errorf("server span parent = %v, want the remote span of traceparent", server.Parent)
+	}
+	if query.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("query span is not a child of the server span")
+	}
+	attrs := attributes(server)
+	for key, want := range map[attribute.Key]attribute.Value{
+		"http.route":         attribute.StringValue("/entries/:id"),
+		"http.target":        attribute.StringValue("/entries/1"),
+		"http.status_code":   attribute.IntValue(200),
+		"list.page_size":     attribute.IntValue(10),
+		"list.page_offset":   attribute.IntValue(20),
+		"list.order_by":      attribute.StringValue("create_time"),
+		"list.filter_fields": attribute.StringSliceValue([]string{"name", "version"}),
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %v, want %v", key, attrs[key].Emit(), want.Emit())
+		}
+	}
+
+	for _, s := range spans {
+		for _, kv := range s.Attributes {
+			if strings.Contains(kv.Value.Emit(), "secret") {
+				t.Errorf("span %s attribute %s = %q records a filter value", s.Name, kv.Key, kv.Value.Emit())
+			}
+		}
+	}
+
+	exporter.Reset()
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/fail", nil)); err != nil {
+		t.Fatal(err)
+	}
+	spans = exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error || attributes(spans[0])["http.status_code"] != attribute.IntValue(503) {
+		t.Errorf("got %+v, want a failed server span with status 503", spans)
+	}
+}
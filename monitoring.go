@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/auth"
+	"github.com/rahul2393/city-falcon-assignment/internal/model"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+	"github.com/rahul2393/city-falcon-assignment/internal/tracing"
+)
+
+const targetHealthTimeout = 2 * time.Second
+
+// slowQueries lists the queries running on the selected targets, or their groups with groupBy=fingerprint.
+func (s Service) slowQueries(c *fiber.Ctx) error {
+	req := model.SlowQueriesRequest{ListParams: listRequest(c, "pid")}
+	switch c.Query("groupBy") {
+	case "":
+	case "fingerprint":
+		// groups are ordered by their longest query whatever orderBy, which orders the queries grouped
+		resp, err := fanOutPage(s, c, "max_duration_ms DESC, fingerprint", req.PageOffset, req.PageSize, func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]*model.SlowQueryGroup, error) {
+			req := req
+			req.PageOffset, req.PageSize = pageOffset, pageSize
+			groups, err := t.Monitor.SlowQueryGroups(ctx, req)
+			for _, g := range groups {
+				g.Target = t.Name
+			}
+			return groups, err
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSON(resp)
+	default:
+		return fiber.NewError(http.StatusBadRequest, "unsupported groupBy: "+c.Query("groupBy"))
+	}
+	raw, err := s.rawRequested(c)
+	if err != nil {
+		return err
+	}
+	resp, err := fanOutPage(s, c, req.OrderBy, req.PageOffset, req.PageSize, func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]*model.SlowQueryRecord, error) {
+		req := req
+		req.PageOffset, req.PageSize = pageOffset, pageSize
+		records, err := t.Monitor.SlowQuery(ctx, req)
+		for _, r := range records {
+			r.Target = t.Name
+		}
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	if !raw {
+		for _, r := range resp {
+			r.Query = s.redactor.Redact(r.Query)
+		}
+	}
+	return c.JSON(resp)
+}
+
+// blockingQueries lists blocked backends together with the backends holding the locks they wait for, paging over
+// the blocking chains.
+func (s Service) blockingQueries(c *fiber.Ctx) error {
+	req := model.BlockingQueriesRequest{ListParams: listRequest(c, "blocked_pid")}
+	raw, err := s.rawRequested(c)
+	if err != nil {
+		return err
+	}
+	resp, err := fanOut(s, c, func(ctx context.Context, t *targets.Target) ([]*model.BlockingQueryRecord, error) {
+		records, err := t.Monitor.BlockingQueries(ctx, req)
+		for _, r := range records {
+			r.Target = t.Name
+		}
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	if !raw {
+		for _, r := range resp {
+			r.BlockedQuery = s.redactor.Redact(r.BlockedQuery)
+			r.BlockingQuery = s.redactor.Redact(r.BlockingQuery)
+		}
+	}
+	return c.JSON(model.PageBlockingTrees(resp, req.PageOffset, req.PageSize))
+}
+
+// slowQueryHistory lists queries recorded by the slow query sampler.
+func (s Service) slowQueryHistory(c *fiber.Ctx) error {
+	req := model.SlowQueryHistoryRequest{ListParams: listRequest(c, "last_seen DESC")}
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fiber.NewError(http.StatusBadRequest, "invalid from: "+err.Error())
+		}
+		req.From = from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fiber.NewError(http.StatusBadRequest, "invalid to: "+err.Error())
+		}
+		req.To = to
+	}
+	raw, err := s.rawRequested(c)
+	if err != nil {
+		return err
+	}
+	resp, err := s.provider.SlowQueryHistory(c.UserContext(), req)
+	if err != nil {
+		s.log(c).Errorf("failed to list slow query history: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return nil
+	}
+	if !raw {
+		for _, r := range resp {
+			r.Query = s.redactor.Redact(r.Query)
+		}
+	}
+	return c.JSON(resp)
+}
+
+// replication lists the standbys streaming from the selected targets.
+func (s Service) replication(c *fiber.Ctx) error {
+	req := model.ReplicationRequest{ListParams: listRequest(c, "application_name")}
+	resp, err := fanOutPage(s, c, req.OrderBy, req.PageOffset, req.PageSize, func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]*model.ReplicationRecord, error) {
+		req := req
+		req.PageOffset, req.PageSize = pageOffset, pageSize
+		records, err := t.Monitor.Replication(ctx, req)
+		for _, r := range records {
+			r.Target = t.Name
+		}
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// walReceivers lists the WAL receivers of the selected targets, i.e. their upstream when they are standbys.
+func (s Service) walReceivers(c *fiber.Ctx) error {
+	req := model.ReplicationRequest{ListParams: listRequest(c, "pid")}
+	resp, err := fanOutPage(s, c, req.OrderBy, req.PageOffset, req.PageSize, func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]*model.WALReceiverRecord, error) {
+		req := req
+		req.PageOffset, req.PageSize = pageOffset, pageSize
+		records, err := t.Monitor.WALReceivers(ctx, req)
+		for _, r := range records {
+			r.Target = t.Name
+		}
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// replicationSlots lists the replication slots of the selected targets.
+func (s Service) replicationSlots(c *fiber.Ctx) error {
+	req := model.ReplicationRequest{ListParams: listRequest(c, "slot_name")}
+	resp, err := fanOutPage(s, c, req.OrderBy, req.PageOffset, req.PageSize, func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]*model.ReplicationSlotRecord, error) {
+		req := req
+		req.PageOffset, req.PageSize = pageOffset, pageSize
+		records, err := t.Monitor.ReplicationSlots(ctx, req)
+		for _, r := range records {
+			r.Target = t.Name
+		}
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// tables reports the size, dead tuples, vacuum and scan statistics of the user tables of the selected targets.
+func (s Service) tables(c *fiber.Ctx) error {
+	req := model.TableHealthRequest{ListParams: listRequest(c, "total_bytes DESC")}
+	resp, err := fanOutPage(s, c, req.OrderBy, req.PageOffset, req.PageSize, func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]*model.TableHealthRecord, error) {
+		req := req
+		req.PageOffset, req.PageSize = pageOffset, pageSize
+		records, err := t.Monitor.TableHealth(ctx, req)
+		for _, r := range records {
+			r.Target = t.Name
+		}
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// indexes reports the usage of the user indexes of the selected targets, flagging unused and duplicate ones.
+func (s Service) indexes(c *fiber.Ctx) error {
+	req := model.TableHealthRequest{ListParams: listRequest(c, "index_bytes DESC")}
+	resp, err := fanOutPage(s, c, req.OrderBy, req.PageOffset, req.PageSize, func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]*model.IndexHealthRecord, error) {
+		req := req
+		req.PageOffset, req.PageSize = pageOffset, pageSize
+		records, err := t.Monitor.IndexHealth(ctx, req)
+		for _, r := range records {
+			r.Target = t.Name
+		}
+		return records, err
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// listTargets reports the health of every monitoring target.
+func (s Service) listTargets(c *fiber.Ctx) error {
+	return c.JSON(s.targets.Health(c.UserContext(), targetHealthTimeout))
+}
+
+// listRequest reads the paging, ordering and filter parameters shared by the list APIs.
+func listRequest(c *fiber.Ctx, defaultOrderBy string) model.ListParams {
+	params := model.ListParams{PageSize: 100, OrderBy: c.Query("orderBy", defaultOrderBy), Filter: c.Query("filter", "")}
+	if v, err := strconv.Atoi(c.Query("pageSize", "100")); err == nil {
+		params.PageSize = v
+	}
+	if v, err := strconv.Atoi(c.Query("pageOffset", "0")); err == nil {
+		params.PageOffset = v
+	}
+	tracing.SetListAttributes(c.UserContext(), params.PageSize, params.PageOffset, params.OrderBy, params.Filter)
+	return params
+}
+
+// fanOut runs fetch against the targets selected by the request, from the :name route parameter or the target
+// query parameter ("*" selecting all of them), and merges the results. Targets that fail are logged and listed
+// in the X-Failed-Targets header; the request fails only when no target answered.
+func fanOut[T any](s Service, c *fiber.Ctx, fetch func(ctx context.Context, t *targets.Target) ([]T, error)) ([]T, error) {
+	selected, err := s.targets.Select(c.Params("name", c.Query("target")))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusNotFound, err.Error())
+	}
+	resp, failed := targets.FanOut(c.UserContext(), selected, fetch)
+	if err := reportFailures(s, c, len(selected), failed); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// fanOutPage runs fetch like fanOut, returning the page of pageSize results starting at pageOffset of all the
+// selected targets ordered by orderBy.
+func fanOutPage[T any](s Service, c *fiber.Ctx, orderBy string, pageOffset, pageSize int, fetch func(ctx context.Context, t *targets.Target, pageOffset, pageSize int) ([]T, error)) ([]T, error) {
+	selected, err := s.targets.Select(c.Params("name", c.Query("target")))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusNotFound, err.Error())
+	}
+	resp, failed := targets.FanOutPage(c.UserContext(), selected, orderBy, pageOffset, pageSize, fetch)
+	if err := reportFailures(s, c, len(selected), failed); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// reportFailures logs the targets that failed and lists them in the X-Failed-Targets header, failing the request
+// when none of the selected targets answered.
+func reportFailures(s Service, c *fiber.Ctx, selected int, failed map[string]error) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(failed))
+	for name, err := range failed {
+		s.log(c).WithField("target", name).Errorf("monitoring query failed: %v", err)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.Set("X-Failed-Targets", strings.Join(names, ","))
+	if len(failed) == selected {
+		return fiber.NewError(http.StatusInternalServerError, "no target answered")
+	}
+	return nil
+}
+
+// rawRequested reports whether the request asks for unredacted query text with raw=true. Only principals with the
+// admin role may do so, or while authentication is disabled requests carrying the ADMIN_TOKEN in the X-Admin-Token
+// header; others get 403.
+func (s Service) rawRequested(c *fiber.Ctx) (bool, error) {
+	if c.Query("raw") != "true" {
+		return false, nil
+	}
+	if p, ok := auth.FromContext(c.UserContext()); ok && p.HasRole(auth.RoleAdmin) {
+		return true, nil
+	}
+	if token := c.Get("X-Admin-Token"); s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1 {
+		return true, nil
+	}
+	return false, fiber.NewError(http.StatusForbidden, "raw query text requires the admin role")
+}
@@ -16,7 +16,7 @@ func (b *Boolean) Capture(values []string) error {
 
 // nolint: govet
 type Expression struct {
-	And []ConditionGrammar `( WS? @@ )*`
+	And []ConditionGrammar `( WS? ( "AND" WS )? @@ )*`
 }
 
 // nolint: govet
@@ -56,7 +56,7 @@ type Value struct {
 var parser = participle.MustBuild(
 	&Expression{},
 	participle.Lexer(lexer.Must(lexer.Regexp(`(?P<WS>\s+)`+
-		`|(?P<Keyword>(?i)\b(?:NOT|IN|TRUE|FALSE)\b)`+
+		`|(?P<Keyword>(?i)\b(?:NOT|AND|IN|TRUE|FALSE)\b)`+
 		`|(?P<Identifier>[a-zA-Z_][a-zA-Z0-9_]*)`+
 		`|(?P<Float>[-+]?\d*\.\d+([eE][-+]?\d+)?)`+
 		`|(?P<Int>[-+]?\d+([eE][-+]?\d+)?)`+
@@ -94,6 +94,8 @@ func Test_ApplyFilters(t *testing.T) {
 				{"not ne and not ne", `not firstName != "fn" not lastName != "ln"`, false, `( NOT "test_user"."first_name" <> 'fn') AND ( NOT "test_user"."last_name" <> 'ln')`},
 
 				{"greater than and less than", `loginCount > 0 loginCount < 5`, false, `("test_user"."login_count" > 0) AND ("test_user"."login_count" < 5)`},
+				{"explicit and", `loginCount > 0 AND loginCount < 5`, false, `("test_user"."login_count" > 0) AND ("test_user"."login_count" < 5)`},
+				{"lowercase and", `not loginCount > 0 and firstName = "A"`, false, `( NOT "test_user"."login_count" > 0) AND ("test_user"."first_name" = 'A')`},
 				{"not greater than and not less than", `not loginCount > 0 not loginCount < 5`, false, `( NOT "test_user"."login_count" > 0) AND ( NOT "test_user"."login_count" < 5)`},
 
 				{"greater than or equal and less than or equal", `loginCount >= 0 loginCount <= 5`, false, `("test_user"."login_count" >= 0) AND ("test_user"."login_count" <= 5)`},
@@ -150,6 +152,7 @@ func Test_ApplyFilters(t *testing.T) {
 				{"unterminated string inside in", `lastName in ("d)`, true, ""},
 				{"empty in", `lastName in ()`, true, ""},
 				{"nesting for non-map", `lastName.foo = "bar"`, true, ""},
+				{"dangling and", `loginCount > 0 AND`, true, ""},
 				{"nested field named as keyword", `props.in IN ("1", "2") props.not = "3" props.true = "t" props.false != "f"`, true, ""},
 			},
 		},
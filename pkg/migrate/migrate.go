@@ -0,0 +1,312 @@
+// Package migrate applies versioned SQL schema migrations to a Postgres database.
+//
+// Migrations are read from files named <version>_<name>.up.sql and <version>_<name>.down.sql, the down file being
+// optional: migrations without one are irreversible, e.g. those adopting tables the migrations did not create. Applied migrations are recorded in the schema_migrations table together with the checksum of their up
+// SQL, which must not change once applied. Runners are serialized with an advisory lock so that several instances
+// starting at once apply each migration once.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	pg "github.com/go-pg/pg/v10"
+)
+
+// lockID is the key of the advisory lock taken by runners.
+const lockID int64 = 0x6d696772617465 // "migrate"
+
+const createTableSQL = `CREATE TABLE IF NOT EXISTS "schema_migrations" (
+	"version" bigint PRIMARY KEY,
+	"name" text NOT NULL,
+	"checksum" text NOT NULL,
+	"applied_at" timestamptz NOT NULL DEFAULT now()
+)`
+
+// ErrIrreversible is returned by Down when it reaches a migration without down SQL.
+var ErrIrreversible = errors.New("irreversible migration")
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status is the state of a migration in a database.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set for applied migrations whose SQL changed since.
+	Modified bool `json:"modified,omitempty"`
+	// Unknown is set for applied migrations that are not in the migration files, e.g. applied by a newer release.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+type appliedMigration struct {
+	tableName struct{} `pg:"schema_migrations"`
+
+	Version   int64     `pg:"version,pk,type:bigint"`
+	Name      string    `pg:"name"`
+	Checksum  string    `pg:"checksum"`
+	AppliedAt time.Time `pg:"applied_at,default:now()"`
+}
+
+// Load reads the migrations found at the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("%s: not a migration file name, want <version>_<name>.(up|down).sql", e.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		sql, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("%s: version %d is already used by %s", e.Name(), version, migration.Name)
+		}
+		if m[3] == "up" {
+			migration.Up = string(sql)
+		} else {
+			migration.Down = string(sql)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up SQL", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations on a database.
+type Migrator struct {
+	db         *pg.DB
+	migrations []*Migration
+}
+
+// New returns a Migrator applying the migrations of fsys to db.
+func New(db *pg.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Close closes the database handle given to New.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Up applies every pending migration in order, each in its own transaction, and returns the ones applied. It
+// refuses to run when an applied migration was modified.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var done []*Migration
+	err := m.locked(ctx, func(conn *pg.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verifyChecksums(applied); err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := conn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ModelContext(ctx, &appliedMigration{
+					Version:  migration.Version,
+					Name:     migration.Name,
+					Checksum: migration.Checksum,
+				}).Insert()
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, latest first, and returns the ones reverted. It stops with
+// ErrIrreversible at a migration without down SQL.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.locked(ctx, func(conn *pg.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+		for _, v := range versions {
+			migration := m.find(v)
+			if migration == nil {
+				return fmt.Errorf("migration %d_%s: not in the migration files", v, applied[v].Name)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s: %w, it has no down SQL", v, migration.Name, ErrIrreversible)
+			}
+			if err := conn.RunInTransaction(ctx, func(tx *pg.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ModelContext(ctx, applied[v]).WherePK().Delete()
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every migration, known or applied, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	applied, err := loadApplied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []*Status
+	for _, migration := range m.migrations {
+		s := &Status{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			s.AppliedAt = &a.AppliedAt
+			s.Modified = a.Checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, a := range applied {
+		a := a
+		statuses = append(statuses, &Status{Version: a.Version, Name: a.Name, AppliedAt: &a.AppliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Verify fails unless every migration is applied unmodified. Migrations applied by newer releases are accepted.
+func (m *Migrator) Verify(ctx context.Context) error {
+	applied, err := loadApplied(ctx, m.db)
+	if err != nil {
+		return err
+	}
+	if err := m.verifyChecksums(applied); err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			return fmt.Errorf("migration %d_%s is not applied", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) verifyChecksums(applied map[int64]*appliedMigration) error {
+	var errs []error
+	for _, migration := range m.migrations {
+		if a, ok := applied[migration.Version]; ok && a.Checksum != migration.Checksum {
+			errs = append(errs, fmt.Errorf("migration %d_%s was modified after being applied", migration.Version, migration.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// locked runs fn on a dedicated connection holding the advisory lock of the runners, creating the migrations table
+// first if needed.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pg.Conn) error) error {
+	conn := m.db.Conn()
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(?)", lockID); err != nil {
+		return fmt.Errorf("pg_advisory_lock(): %w", err)
+	}
+	defer func() {
+		// unlock even when ctx is done, the lock would otherwise be held until the connection closes
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(?)", lockID)
+	}()
+	if _, err := conn.ExecContext(ctx, createTableSQL); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// loadApplied returns the applied migrations by version, none when the migrations table does not exist yet.
+func loadApplied(ctx context.Context, db pg.DBI) (map[int64]*appliedMigration, error) {
+	var exists bool
+	if _, err := db.QueryOneContext(ctx, pg.Scan(&exists), "SELECT to_regclass('schema_migrations') IS NOT NULL"); err != nil {
+		return nil, err
+	}
+	applied := map[int64]*appliedMigration{}
+	if !exists {
+		return applied, nil
+	}
+	var rows []*appliedMigration
+	if err := db.ModelContext(ctx, &rows).Select(); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
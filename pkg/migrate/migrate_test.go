@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		want     []int64
+		wantDown []bool
+		wantErr  bool
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"0010_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t (a);")},
+				"0002_create_t.up.sql":    {Data: []byte("CREATE TABLE t (a int);")},
+				"0002_create_t.down.sql":  {Data: []byte("DROP TABLE t;")},
+				"0001_baseline.up.sql":    {Data: []byte("SELECT 1;")},
+				"0001_baseline.down.sql":  {Data: []byte("SELECT 1;")},
+				"0010_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+			},
+			want:     []int64{1, 2, 10},
+			wantDown: []bool{true, true, true},
+		},
+		{
+			name:     "down is optional",
+			files:    fstest.MapFS{"1_irreversible.up.sql": {Data: []byte("SELECT 1;")}},
+			want:     []int64{1},
+			wantDown: []bool{false},
+		},
+		{
+			name:  "empty",
+			files: fstest.MapFS{},
+		},
+		{
+			name:    "missing up",
+			files:   fstest.MapFS{"1_a.down.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: true,
+		},
+		{
+			name: "version used twice",
+			files: fstest.MapFS{
+				"1_a.up.sql": {Data: []byte("SELECT 1;")},
+				"1_b.up.sql": {Data: []byte("SELECT 2;")},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "bad name",
+			files:   fstest.MapFS{"create_t.sql": {Data: []byte("SELECT 1;")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Load() returned %d migrations, want %d", len(got), len(tt.want))
+			}
+			for i, m := range got {
+				if m.Version != tt.want[i] {
+					t.Errorf("migration #%d version = %d, want %d", i, m.Version, tt.want[i])
+				}
+				if (m.Down != "") != tt.wantDown[i] {
+					t.Errorf("migration %d has down SQL = %v, want %v", m.Version, m.Down != "", tt.wantDown[i])
+				}
+				if len(m.Checksum) != 64 {
+					t.Errorf("migration %d checksum = %q, want a sha256", m.Version, m.Checksum)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_ChecksumCoversUpSQL(t *testing.T) {
+	a, err := Load(fstest.MapFS{"1_a.up.sql": {Data: []byte("SELECT 1;")}, "1_a.down.sql": {Data: []byte("x")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(fstest.MapFS{"1_a.up.sql": {Data: []byte("SELECT 2;")}, "1_a.down.sql": {Data: []byte("x")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(fstest.MapFS{"1_a.up.sql": {Data: []byte("SELECT 1;")}, "1_a.down.sql": {Data: []byte("y")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a[0].Checksum == b[0].Checksum {
+		t.Errorf("checksum does not change with the up SQL")
+	}
+	if a[0].Checksum != c[0].Checksum {
+		t.Errorf("checksum changes with the down SQL")
+	}
+}
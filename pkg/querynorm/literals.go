@@ -0,0 +1,151 @@
+package querynorm
+
+import "strings"
+
+// Literal locates a constant or bind parameter in a query.
+type Literal struct {
+	// Start and End are the byte offsets of the literal in the query, including quotes.
+	Start, End int
+	// Param is true for bind parameters such as $1, which carry no value in the query text.
+	Param bool
+	// Column is the lower-cased name of the column the literal is compared with or assigned to, or empty when
+	// it cannot be determined from the surrounding tokens.
+	Column string
+}
+
+var comparisonOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"~": true, "~*": true, "!~": true, "!~*": true, "like": true, "ilike": true,
+}
+
+// Literals returns the literals and bind parameters of query in order of appearance. The column of a literal is
+// recognised in comparisons (col = 'x', 'x' = col, col LIKE 'x', col BETWEEN 1 AND 2), IN lists, UPDATE ... SET
+// assignments and INSERT ... (cols) VALUES (...) tuples.
+func Literals(query string) []Literal {
+	tokens := tokenize(query)
+	var literals []Literal
+	for i, t := range tokens {
+		if !t.isValue() {
+			continue
+		}
+		literals = append(literals, Literal{
+			Start:  t.start,
+			End:    t.end,
+			Param:  t.kind == tokenParam,
+			Column: columnOf(tokens, i),
+		})
+	}
+	return literals
+}
+
+// columnOf returns the column associated with the value at tokens[i].
+func columnOf(tokens []token, i int) string {
+	prev := tokenAt(tokens, i-1)
+	switch {
+	case comparisonOperators[prev.text]:
+		if tokenAt(tokens, i-2).text == "not" {
+			return identName(tokenAt(tokens, i-3))
+		}
+		return identName(tokenAt(tokens, i-2))
+
+	case prev.text == "between":
+		return identName(tokenAt(tokens, i-2))
+
+	case prev.text == "and" && tokenAt(tokens, i-2).isValue() && tokenAt(tokens, i-3).text == "between":
+		return identName(tokenAt(tokens, i-4))
+
+	case prev.text == "(" || prev.text == ",":
+		return listColumn(tokens, i)
+	}
+
+	if next := tokenAt(tokens, i+1); comparisonOperators[next.text] && next.text != "like" && next.text != "ilike" {
+		return identName(tokenAt(tokens, i+2))
+	}
+	return ""
+}
+
+// listColumn resolves the column of a value inside a parenthesised list: an IN list or a VALUES tuple.
+func listColumn(tokens []token, i int) string {
+	position := 0
+	open := i - 1
+	for ; open >= 0 && tokens[open].text != "("; open-- {
+		switch {
+		case tokens[open].text == ",":
+			position++
+		case !tokens[open].isValue():
+			return ""
+		}
+	}
+	if open < 1 {
+		return ""
+	}
+
+	before := tokens[open-1]
+	if before.text == "in" {
+		if open >= 2 && tokens[open-2].text == "not" {
+			return identName(tokenAt(tokens, open-3))
+		}
+		return identName(tokenAt(tokens, open-2))
+	}
+
+	// Skip preceding tuples of a multi-row VALUES list.
+	k := open - 1
+	for k >= 1 && tokens[k].text == "," && tokens[k-1].text == ")" {
+		depth := 0
+		for k--; k >= 0; k-- {
+			if tokens[k].text == ")" {
+				depth++
+			} else if tokens[k].text == "(" {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		k--
+	}
+	if k < 0 || tokens[k].text != "values" {
+		return ""
+	}
+
+	// tokens[k-1] must close the column list of the INSERT.
+	if k < 1 || tokens[k-1].text != ")" {
+		return ""
+	}
+	var columns []string
+	for j := k - 2; j >= 0 && tokens[j].text != "("; j-- {
+		if tokens[j].text == "," {
+			continue
+		}
+		if name := identName(tokens[j]); name != "" {
+			columns = append(columns, name)
+			continue
+		}
+		return ""
+	}
+	if position >= len(columns) {
+		return ""
+	}
+	return columns[len(columns)-1-position]
+}
+
+func tokenAt(tokens []token, k int) token {
+	if k < 0 || k >= len(tokens) {
+		return token{}
+	}
+	return tokens[k]
+}
+
+// identName returns the unquoted name of an identifier token, or empty if t is not an identifier.
+func identName(t token) string {
+	switch t.kind {
+	case tokenWord:
+		if isKeyword(t.text) {
+			return ""
+		}
+		return t.text
+	case tokenQuotedIdent:
+		return strings.ToLower(strings.ReplaceAll(strings.Trim(t.text, `"`), `""`, `"`))
+	}
+	return ""
+}
@@ -0,0 +1,311 @@
+// Package querynorm reduces SQL statements to their shape so that executions differing only in literal values
+// can be recognised as the same statement.
+package querynorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Placeholder replaces every literal and bind parameter in a normalized query.
+const Placeholder = "?"
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota + 1
+	tokenQuotedIdent
+	tokenLiteral
+	tokenParam
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// start and end are the byte offsets of the token in the original query.
+	start, end int
+}
+
+func (t token) isValue() bool {
+	return t.kind == tokenLiteral || t.kind == tokenParam
+}
+
+// Normalize returns query with comments removed, string, numeric and dollar-quoted literals as well as bind
+// parameters replaced by Placeholder, lists of placeholders inside IN (...) collapsed to a single one, unquoted
+// identifiers and keywords lower-cased and whitespace collapsed to single spaces.
+//
+// Normalize never fails: queries truncated by track_activity_query_size are normalized up to where they end.
+func Normalize(query string) string {
+	return render(collapseIn(tokenize(query)))
+}
+
+// Fingerprint returns a stable hex identifier of the normalized form of query.
+func Fingerprint(query string) string {
+	sum := sha256.Sum256([]byte(Normalize(query)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func tokenize(s string) []token {
+	var tokens []token
+	emit := func(kind tokenKind, text string, start, end int) int {
+		tokens = append(tokens, token{kind: kind, text: text, start: start, end: end})
+		return end
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case isSpace(c):
+			i++
+
+		case c == '-' && i+1 < len(s) && s[i+1] == '-':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			i = skipBlockComment(s, i)
+
+		case c == '\'':
+			i = emit(tokenLiteral, Placeholder, i, skipString(s, i+1, false))
+
+		case (c == 'e' || c == 'E' || c == 'b' || c == 'B' || c == 'x' || c == 'X' || c == 'n' || c == 'N') &&
+			i+1 < len(s) && s[i+1] == '\'':
+			i = emit(tokenLiteral, Placeholder, i, skipString(s, i+2, c == 'e' || c == 'E'))
+
+		case c == '"':
+			j := i + 1
+			for j < len(s) {
+				if s[j] == '"' {
+					if j+1 < len(s) && s[j+1] == '"' {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			i = emit(tokenQuotedIdent, s[i:j], i, j)
+
+		case c == '$' && i+1 < len(s) && isDigit(s[i+1]):
+			j := i + 1
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			i = emit(tokenParam, Placeholder, i, j)
+
+		case c == '$':
+			if end, ok := skipDollarQuoted(s, i); ok {
+				i = emit(tokenLiteral, Placeholder, i, end)
+				continue
+			}
+			i = emit(tokenPunct, "$", i, i+1)
+
+		case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(s[i+1])):
+			i = emit(tokenLiteral, Placeholder, i, skipNumber(s, i))
+
+		case isWordStart(c):
+			j := i + 1
+			for j < len(s) && isWordPart(s[j]) {
+				j++
+			}
+			i = emit(tokenWord, strings.ToLower(s[i:j]), i, j)
+
+		case (c == '-' || c == '+') && i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '.') && expectsOperand(tokens):
+			i = emit(tokenLiteral, Placeholder, i, skipNumber(s, i+1))
+
+		default:
+			j := i + 1
+			if strings.IndexByte(operatorChars, c) >= 0 {
+				for j < len(s) && strings.IndexByte(operatorChars, s[j]) >= 0 &&
+					!(s[j] == '-' && j+1 < len(s) && s[j+1] == '-') &&
+					!(s[j] == '/' && j+1 < len(s) && s[j+1] == '*') {
+					j++
+				}
+			}
+			i = emit(tokenPunct, s[i:j], i, j)
+		}
+	}
+	return tokens
+}
+
+// expectsOperand reports whether a sign at the current position is unary, i.e. part of a numeric literal.
+func expectsOperand(tokens []token) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	prev := tokens[len(tokens)-1]
+	switch prev.kind {
+	case tokenPunct:
+		return prev.text != ")" && prev.text != "]"
+	case tokenWord:
+		return isKeyword(prev.text)
+	}
+	return false
+}
+
+const operatorChars = "+-*/<>=~!@#%^&|`?:"
+
+// skipString returns the index after the closing quote of a string literal whose content starts at i.
+func skipString(s string, i int, backslashEscapes bool) int {
+	for i < len(s) {
+		switch {
+		case backslashEscapes && s[i] == '\\':
+			i += 2
+		case s[i] == '\'':
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i += 2
+				continue
+			}
+			return i + 1
+		default:
+			i++
+		}
+	}
+	return len(s)
+}
+
+// skipDollarQuoted returns the index after a $tag$...$tag$ literal starting at i, ok is false if s[i] does not
+// start a dollar quote.
+func skipDollarQuoted(s string, i int) (int, bool) {
+	j := i + 1
+	for j < len(s) && isWordPart(s[j]) && s[j] != '$' {
+		j++
+	}
+	if j >= len(s) || s[j] != '$' {
+		return 0, false
+	}
+	tag := s[i : j+1]
+	end := strings.Index(s[j+1:], tag)
+	if end < 0 {
+		return len(s), true
+	}
+	return j + 1 + end + len(tag), true
+}
+
+func skipBlockComment(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case s[i] == '/' && i+1 < len(s) && s[i+1] == '*':
+			depth++
+			i += 2
+		case s[i] == '*' && i+1 < len(s) && s[i+1] == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(s)
+}
+
+func skipNumber(s string, i int) int {
+	if s[i] == '0' && i+1 < len(s) && (s[i+1] == 'x' || s[i+1] == 'X') {
+		i += 2
+		for i < len(s) && (isDigit(s[i]) || strings.IndexByte("abcdefABCDEF_", s[i]) >= 0) {
+			i++
+		}
+		return i
+	}
+	for i < len(s) && (isDigit(s[i]) || s[i] == '_') {
+		i++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			i = j
+			for i < len(s) && isDigit(s[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+// collapseIn replaces IN (?, ?, ...) with IN (?).
+func collapseIn(tokens []token) []token {
+	out := tokens[:0:0]
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+		if tokens[i].kind != tokenWord || tokens[i].text != "in" || i+1 >= len(tokens) || tokens[i+1].text != "(" {
+			continue
+		}
+		j := i + 2
+		for j < len(tokens) && (tokens[j].isValue() || tokens[j].text == ",") {
+			j++
+		}
+		if j > i+2 && j < len(tokens) && tokens[j].text == ")" {
+			out = append(out, tokens[i+1], token{kind: tokenLiteral, text: Placeholder}, tokens[j])
+			i = j
+		}
+	}
+	return out
+}
+
+func render(tokens []token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && needsSpace(tokens[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+func needsSpace(prev, curr token) bool {
+	switch {
+	case prev.text == "(" || prev.text == "." || prev.text == "::" || prev.text == "[":
+		return false
+	case curr.text == ")" || curr.text == "," || curr.text == "." || curr.text == "::" || curr.text == ";" ||
+		curr.text == "]" || curr.text == "[":
+		return false
+	case curr.text == "(" && (prev.kind == tokenWord || prev.kind == tokenQuotedIdent):
+		// Function calls keep their parenthesis attached, keywords like IN and VALUES are separated.
+		return isKeyword(prev.text)
+	}
+	return true
+}
+
+var keywords = map[string]bool{
+	"in": true, "values": true, "and": true, "or": true, "not": true, "exists": true, "as": true, "on": true,
+	"from": true, "join": true, "where": true, "select": true, "using": true, "all": true,
+	"into": true, "over": true, "filter": true, "when": true, "then": true, "else": true, "set": true,
+	"by": true, "limit": true, "offset": true, "between": true, "like": true, "ilike": true, "is": true,
+	"case": true, "returning": true, "having": true,
+}
+
+func isKeyword(word string) bool {
+	return keywords[word]
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
@@ -0,0 +1,95 @@
+package querynorm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"whitespace", "SELECT  *\n\tFROM   users ", "select * from users"},
+		{"string literal", `SELECT * FROM users WHERE email = 'a@b.c'`, "select * from users where email = ?"},
+		{"escaped quote", `SELECT 'it''s'`, "select ?"},
+		{"escape string", `SELECT E'a\'b' , 1`, "select ?, ?"},
+		{"numbers", "SELECT 1, 2.5, .5, 1e10, 0x1F", "select ?, ?, ?, ?, ?"},
+		{"negative number", "SELECT * FROM t WHERE a = -1 AND b > +2.0", "select * from t where a = ? and b > ?"},
+		{"binary minus", "SELECT a - 1, f(x) - 2 FROM t", "select a - ?, f(x) - ? from t"},
+		{"identifiers with digits", "SELECT t1.c2 FROM t1", "select t1.c2 from t1"},
+		{"quoted identifier", `SELECT "Name" FROM "Users"`, `select "Name" from "Users"`},
+		{"bind parameters", "UPDATE t SET a = $1 WHERE id = $2", "update t set a = ? where id = ?"},
+		{"dollar quoted", "SELECT $$abc$$, $tag$x$y$tag$", "select ?, ?"},
+		{"in list", "SELECT * FROM t WHERE id IN (1, 2, 3)", "select * from t where id in (?)"},
+		{"in list of strings", "SELECT * FROM t WHERE id in ('a','b')", "select * from t where id in (?)"},
+		{"in subquery", "SELECT * FROM t WHERE id IN (SELECT id FROM u WHERE x = 1)", "select * from t where id in (select id from u where x = ?)"},
+		{"line comment", "SELECT 1 -- trailing\nFROM t", "select ? from t"},
+		{"block comment", "SELECT /* a /* nested */ b */ 1", "select ?"},
+		{"cast", "SELECT '2020-01-01'::date", "select ?::date"},
+		{"function call", "SELECT count(*) FROM t WHERE now() > x", "select count(*) from t where now() > x"},
+		{"insert values", "INSERT INTO t (a, b) VALUES (1, 'x')", "insert into t(a, b) values (?, ?)"},
+		{"truncated string", "SELECT * FROM t WHERE a = 'unterminat", "select * from t where a = ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.query); got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	same := []string{
+		"SELECT * FROM users WHERE id IN (1, 2, 3) AND email = 'a@b.c'",
+		"select *\nfrom users where id in (4) and email = 'x@y.z'",
+		"SELECT * FROM users /* from app */ WHERE id IN ($1, $2) AND email = $3",
+	}
+	want := Fingerprint(same[0])
+	if len(want) != 16 {
+		t.Fatalf("Fingerprint() = %q, want 16 hex characters", want)
+	}
+	for _, q := range same[1:] {
+		if got := Fingerprint(q); got != want {
+			t.Errorf("Fingerprint(%q) = %q, want %q", q, got, want)
+		}
+	}
+	if got := Fingerprint("SELECT * FROM accounts WHERE id IN (1)"); got == want {
+		t.Errorf("Fingerprint() of a different statement must differ, got %q", got)
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string // "text@column" for every literal
+	}{
+		{"none", "SELECT * FROM t", nil},
+		{"comparison", "SELECT * FROM users WHERE email = 'a@b.c' AND age > 30", []string{"'a@b.c'@email", "30@age"}},
+		{"qualified and quoted", `SELECT * FROM users u WHERE u."Password" <> 'x'`, []string{"'x'@password"}},
+		{"reversed", "SELECT * FROM t WHERE 'tok' = api_token", []string{"'tok'@api_token"}},
+		{"like", "SELECT * FROM t WHERE name NOT LIKE 'a%'", []string{"'a%'@name"}},
+		{"between", "SELECT * FROM t WHERE id BETWEEN 1 AND 2", []string{"1@id", "2@id"}},
+		{"in list", "SELECT * FROM t WHERE token IN ('a', 'b')", []string{"'a'@token", "'b'@token"}},
+		{"not in list", "SELECT * FROM t WHERE token NOT IN (1)", []string{"1@token"}},
+		{"update", "UPDATE t SET password = $1, name = 'n' WHERE id = 5", []string{"$1@password", "'n'@name", "5@id"}},
+		{"insert", "INSERT INTO t (id, email) VALUES (1, 'e'), (2, 'f')", []string{"1@id", "'e'@email", "2@id", "'f'@email"}},
+		{"function argument", "SELECT md5('secret')", []string{"'secret'@"}},
+		{"dollar quoted", "SELECT * FROM t WHERE body = $x$it's$x$", []string{"$x$it's$x$@body"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, l := range Literals(tt.query) {
+				got = append(got, tt.query[l.Start:l.End]+"@"+l.Column)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Literals() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
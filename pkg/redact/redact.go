@@ -0,0 +1,138 @@
+// Package redact removes sensitive values from SQL text before it leaves the service, either in API responses or
+// in logs.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rahul2393/city-falcon-assignment/pkg/querynorm"
+)
+
+// Mode selects what a Redactor removes.
+type Mode string
+
+const (
+	// ModeNone leaves queries untouched.
+	ModeNone Mode = "none"
+	// ModeLiterals replaces every string, numeric and dollar-quoted literal with querynorm.Placeholder.
+	ModeLiterals Mode = "literals"
+	// ModeRules applies only the configured rules.
+	ModeRules Mode = "rules"
+)
+
+// DefaultReplacement is used for Pattern rules without a Replacement.
+const DefaultReplacement = "<redacted>"
+
+// Rule describes a value to redact. Exactly one of Column and Pattern must be set.
+type Rule struct {
+	// Column is a regular expression matched against column names; literals compared with or assigned to a
+	// matching column are replaced with querynorm.Placeholder.
+	Column string `json:"column,omitempty"`
+	// Pattern is a regular expression matched against the query text, every match is replaced with Replacement.
+	Pattern string `json:"pattern,omitempty"`
+	// Replacement replaces Pattern matches and may refer to submatches ($1), defaults to DefaultReplacement.
+	Replacement string `json:"replacement,omitempty"`
+}
+
+type compiledRule struct {
+	column      *regexp.Regexp
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// Redactor redacts query text according to a Mode and a set of Rules. The zero value and a nil *Redactor leave
+// queries untouched.
+type Redactor struct {
+	mode  Mode
+	rules []compiledRule
+}
+
+// New compiles rules into a Redactor. Rules are applied in ModeLiterals and ModeRules; in ModeLiterals they only
+// matter for Pattern rules since every literal is already removed.
+func New(mode Mode, rules []Rule) (*Redactor, error) {
+	switch mode {
+	case ModeNone, ModeLiterals, ModeRules:
+	case "":
+		mode = ModeLiterals
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q", mode)
+	}
+
+	r := &Redactor{mode: mode}
+	for i, rule := range rules {
+		var c compiledRule
+		var err error
+		switch {
+		case rule.Column != "" && rule.Pattern != "":
+			return nil, fmt.Errorf("rule #%d: column and pattern are mutually exclusive", i+1)
+		case rule.Column != "":
+			if c.column, err = regexp.Compile(rule.Column); err != nil {
+				return nil, fmt.Errorf("rule #%d: column: %v", i+1, err)
+			}
+		case rule.Pattern != "":
+			if c.pattern, err = regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("rule #%d: pattern: %v", i+1, err)
+			}
+			c.replacement = rule.Replacement
+			if c.replacement == "" {
+				c.replacement = DefaultReplacement
+			}
+		default:
+			return nil, fmt.Errorf("rule #%d: one of column or pattern is required", i+1)
+		}
+		r.rules = append(r.rules, c)
+	}
+	return r, nil
+}
+
+// Mode returns the mode of r.
+func (r *Redactor) Mode() Mode {
+	if r == nil || r.mode == "" {
+		return ModeNone
+	}
+	return r.mode
+}
+
+// Redact returns query with sensitive values removed.
+func (r *Redactor) Redact(query string) string {
+	if r.Mode() == ModeNone || query == "" {
+		return query
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, l := range querynorm.Literals(query) {
+		if l.Param || !r.redactLiteral(l) {
+			continue
+		}
+		b.WriteString(query[last:l.Start])
+		b.WriteString(querynorm.Placeholder)
+		last = l.End
+	}
+	b.WriteString(query[last:])
+	query = b.String()
+
+	for _, rule := range r.rules {
+		if rule.pattern != nil {
+			query = rule.pattern.ReplaceAllString(query, rule.replacement)
+		}
+	}
+	return query
+}
+
+func (r *Redactor) redactLiteral(l querynorm.Literal) bool {
+	if r.mode == ModeLiterals {
+		return true
+	}
+	if l.Column == "" {
+		return false
+	}
+	for _, rule := range r.rules {
+		if rule.column != nil && rule.column.MatchString(l.Column) {
+			return true
+		}
+	}
+	return false
+}
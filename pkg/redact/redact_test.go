@@ -0,0 +1,63 @@
+package redact
+
+import "testing"
+
+func TestRedactor_Redact(t *testing.T) {
+	rules := []Rule{
+		{Column: `(?i)^(email|password|.*token)$`},
+		{Pattern: `[\w.+-]+@[\w-]+\.[\w.]+`, Replacement: "<email>"},
+	}
+	tests := []struct {
+		name  string
+		mode  Mode
+		rules []Rule
+		query string
+		want  string
+	}{
+		{"none", ModeNone, rules, "SELECT * FROM u WHERE email = 'a@b.c'", "SELECT * FROM u WHERE email = 'a@b.c'"},
+		{"literals", ModeLiterals, nil, "SELECT * FROM u WHERE email = 'a@b.c' AND id IN (1, 2)", "SELECT * FROM u WHERE email = ? AND id IN (?, ?)"},
+		{"literals keeps params", ModeLiterals, nil, "UPDATE u SET password = $1 WHERE id = 7", "UPDATE u SET password = $1 WHERE id = ?"},
+		{"literals with pattern", ModeLiterals, rules, "SELECT 1 /* user a@b.c */", "SELECT ? /* user <email> */"},
+		{"rules by column", ModeRules, rules, "UPDATE u SET password = 'hunter2', name = 'bob' WHERE api_token = 'abc'", "UPDATE u SET password = ?, name = 'bob' WHERE api_token = ?"},
+		{"rules by insert column", ModeRules, rules, "INSERT INTO u (name, Email) VALUES ('bob', 'x')", "INSERT INTO u (name, Email) VALUES ('bob', ?)"},
+		{"rules by pattern", ModeRules, rules, "SELECT * FROM u WHERE lower(name) = 'c@d.io'", "SELECT * FROM u WHERE lower(name) = '<email>'"},
+		{"default mode", "", nil, "SELECT 'x'", "SELECT ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.mode, tt.rules)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if got := r.Redact(tt.query); got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var nilRedactor *Redactor
+	if got := nilRedactor.Redact("SELECT 'x'"); got != "SELECT 'x'" {
+		t.Errorf("nil Redactor changed the query: %q", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  Mode
+		rules []Rule
+	}{
+		{"unknown mode", "everything", nil},
+		{"empty rule", ModeRules, []Rule{{}}},
+		{"column and pattern", ModeRules, []Rule{{Column: "a", Pattern: "b"}}},
+		{"invalid column", ModeRules, []Rule{{Column: "("}}},
+		{"invalid pattern", ModeRules, []Rule{{Pattern: "["}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.mode, tt.rules); err == nil {
+				t.Errorf("New() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rahul2393/city-falcon-assignment/internal/repository/dataprovider"
+	"github.com/rahul2393/city-falcon-assignment/internal/targets"
+)
+
+// workers runs the background workers of the service until they are stopped.
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{ctx: ctx, cancel: cancel}
+}
+
+// Go runs run in a goroutine with a context cancelled by Stop.
+func (w *workers) Go(run func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		run(w.ctx)
+	}()
+}
+
+// Stop cancels the workers and waits for them to return.
+func (w *workers) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// shutdown stops the service in dependency order: the HTTP server stops accepting connections and waits up to
+// timeout for the requests in progress, then the background workers stop, the connection pools close, the
+// pending spans are exported and the logs flushed.
+func shutdown(app *fiber.App, timeout time.Duration, bg *workers, registry *targets.Registry, repo dataprovider.Provider,
+	shutdownTracing func(context.Context) error, logger *logrus.Entry) {
+	logger.Infof("shutting down, waiting up to %s for requests in progress", timeout)
+	if err := app.ShutdownWithTimeout(timeout); err != nil {
+		logger.Errorf("failed to drain HTTP connections: %v", err)
+	}
+	bg.Stop()
+	for _, t := range registry.All() {
+		if t.Monitor == dataprovider.Monitor(repo) {
+			continue
+		}
+		if err := t.Monitor.Close(); err != nil {
+			logger.WithField("target", t.Name).Errorf("failed to close connection pool: %v", err)
+		}
+	}
+	if err := repo.Close(); err != nil {
+		logger.Errorf("failed to close database connection pool: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Errorf("failed to flush traces: %v", err)
+	}
+	logger.Info("shutdown complete")
+	if f, ok := logger.Logger.Out.(interface{ Sync() error }); ok {
+		_ = f.Sync()
+	}
+}
@@ -106,7 +106,7 @@ func (util *TestUtil) InitDB() error {
 		return err
 	}
 
-	persist, err := postgres.NewRepository(util.DBURL, true, logrus.New().WithField("test", true))
+	persist, err := postgres.NewRepository(postgres.Options{URL: util.DBURL}, true, postgres.NewQueryLog(postgres.QueryLogOptions{SampleRate: 1}), logrus.New().WithField("test", true), nil)
 	if err != nil {
 		return err
 	}